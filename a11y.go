@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// a11yFinding is an accessibility problem in the rendered site, BrandName is empty when it isn't
+// inside a product's card or page
+type a11yFinding struct {
+	BrandName  string
+	SourceFile string
+	Rule       string
+	Message    string
+}
+
+// the rendered HTML comes from our own templates, so matching tags with regexps is enough to catch
+// the mistakes they can make without pulling in an HTML parser
+var (
+	a11yLinkRe     = regexp.MustCompile(`(?is)<a\b([^>]*)>(.*?)</a>`)
+	a11yImgRe      = regexp.MustCompile(`(?is)<img\b[^>]*>`)
+	a11yAltRe      = regexp.MustCompile(`(?is)\balt\s*=`)
+	a11yLabelRe    = regexp.MustCompile(`(?is)\b(aria-label|aria-labelledby|title)\s*=\s*"[^"]*\S[^"]*"`)
+	a11yImgAltRe   = regexp.MustCompile(`(?is)<img\b[^>]*\balt\s*=\s*"[^"]*\S[^"]*"`)
+	a11yTagRe      = regexp.MustCompile(`(?s)<[^>]*>`)
+	a11ySlugRe     = regexp.MustCompile(`data-slug="([^"]*)"`)
+	a11yBareURLRe  = regexp.MustCompile(`^(https?://|www\.)\S+$`)
+	a11yCardMarker = `class="drug-card"`
+)
+
+// checkAccessibility scans the rendered index pages (indexPages, relative to outputDir) and product
+// pages for links without any text, images without alt text, and savings descriptions that are only
+// a URL
+func checkAccessibility(outputDir string, indexPages []string, products []medcatalog.Product) ([]a11yFinding, error) {
+	bySlug := map[string]medcatalog.Product{}
+	for _, p := range products {
+		bySlug[p.Slug] = p
+	}
+	findings := []a11yFinding{}
+	for _, p := range products {
+		for _, s := range p.Savings {
+			if a11yBareURLRe.MatchString(strings.TrimSpace(s.Description)) {
+				findings = append(findings, a11yFinding{BrandName: p.BrandName, SourceFile: p.SourceFile(), Rule: "a11y_bare_url_description",
+					Message: fmt.Sprintf("%s description is just a URL (%s), describe the program instead", s.Type, s.Description)})
+			}
+		}
+	}
+
+	for _, page := range indexPages {
+		index, err := readRenderedPage(outputDir, page)
+		if err != nil {
+			return nil, err
+		}
+		// index cards carry their product's slug, anything before the first card is page chrome
+		sections := strings.Split(index, a11yCardMarker)
+		for i, section := range sections {
+			var p medcatalog.Product
+			if i > 0 {
+				if m := a11ySlugRe.FindStringSubmatch(section); m != nil {
+					p = bySlug[m[1]]
+				}
+			}
+			findings = append(findings, scanA11y(section, page, p)...)
+		}
+	}
+	for _, p := range products {
+		page := "products/" + p.Slug + ".html"
+		content, err := readRenderedPage(outputDir, page)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, scanA11y(content, page, p)...)
+	}
+	return findings, nil
+}
+
+func readRenderedPage(outputDir, page string) (string, error) {
+	content, err := os.ReadFile(filepath.Join(outputDir, page))
+	if err != nil {
+		return "", errors.Join(errors.New("failed reading rendered "+page+" for the accessibility check"), err)
+	}
+	return string(content), nil
+}
+
+// scanA11y looks for links without text and images without alt text in a chunk of HTML
+func scanA11y(content, page string, p medcatalog.Product) []a11yFinding {
+	findings := []a11yFinding{}
+	add := func(rule, message string) {
+		findings = append(findings, a11yFinding{BrandName: p.BrandName, SourceFile: p.SourceFile(), Rule: rule, Message: message})
+	}
+	for _, m := range a11yLinkRe.FindAllStringSubmatch(content, -1) {
+		attrs, inner := m[1], m[2]
+		text := strings.TrimSpace(html.UnescapeString(a11yTagRe.ReplaceAllString(inner, "")))
+		if text == "" && !a11yLabelRe.MatchString(attrs) && !a11yImgAltRe.MatchString(inner) {
+			add("a11y_empty_link", fmt.Sprintf("link %s in %s has no text or label", strings.Join(strings.Fields("<a"+attrs+">"), " "), page))
+		}
+	}
+	for _, img := range a11yImgRe.FindAllString(content, -1) {
+		if !a11yAltRe.MatchString(img) {
+			add("a11y_missing_alt", fmt.Sprintf("image %s in %s has no alt attribute", strings.Join(strings.Fields(img), " "), page))
+		}
+	}
+	return findings
+}
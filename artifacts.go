@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// artifactWriter writes rendered output, skipping files whose content hasn't changed so their mtimes
+// stay put and there's no churn in the output tree
+type artifactWriter struct {
+	dir     string
+	written int
+	skipped int
+}
+
+func newArtifactWriter(dir string) *artifactWriter {
+	return &artifactWriter{dir: dir}
+}
+
+// Write writes content to name (relative to the output directory) unless it's already there
+func (w *artifactWriter) Write(name string, content []byte) error {
+	path := filepath.Join(w.dir, name)
+	existing, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Join(errors.New("failed reading existing "+path), err)
+	}
+	if err == nil && sha256.Sum256(existing) == sha256.Sum256(content) {
+		w.skipped++
+		fmt.Println(path + " unchanged.")
+		return nil
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return errors.Join(errors.New("failed creating directory "+filepath.Dir(path)+" for "+name), err)
+	}
+	if err = os.WriteFile(path, content, 0o644); err != nil {
+		return errors.Join(errors.New("failed writing "+path), err)
+	}
+	w.written++
+	fmt.Println(path + " rendered successfully.")
+	return nil
+}
+
+// WriteFunc renders into a buffer with fn then writes it like Write
+func (w *artifactWriter) WriteFunc(name string, fn func(*bytes.Buffer) error) error {
+	var buf bytes.Buffer
+	if err := fn(&buf); err != nil {
+		return err
+	}
+	return w.Write(name, buf.Bytes())
+}
+
+func (w *artifactWriter) Report() {
+	fmt.Printf("%d artifact(s) written, %d unchanged\n", w.written, w.skipped)
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestArtifactWriterSkipsUnchangedPages(t *testing.T) {
+	dir := t.TempDir()
+	products := loadTestCatalog(t)
+	if err := renderProductPages(newArtifactWriter(dir), products); err != nil {
+		t.Fatalf("renderProductPages() failed: %v", err)
+	}
+	unchangedPage := filepath.Join(dir, productPagesDir+products[0].Slug+".html")
+	before, err := os.Stat(unchangedPage)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	products[1].DoseFrequency = "Twice Daily"
+	w := newArtifactWriter(dir)
+	if err = renderProductPages(w, products); err != nil {
+		t.Fatalf("renderProductPages() failed: %v", err)
+	}
+	if w.written != 1 || w.skipped != len(products)-1 {
+		t.Errorf("re-render after changing one product wrote %d and skipped %d, want 1 and %d", w.written, w.skipped, len(products)-1)
+	}
+	after, err := os.Stat(unchangedPage)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.ModTime().Equal(before.ModTime()) {
+		t.Errorf("unchanged product's page was rewritten, mtime %v became %v", before.ModTime(), after.ModTime())
+	}
+}
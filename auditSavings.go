@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// savingsOutlierGap is how many fewer savings programs a product can have than the best covered
+// similar product before it's flagged for review
+const savingsOutlierGap = 2
+
+type savingsAuditGroup struct {
+	Key      string
+	Products []medcatalog.Product
+	Outlier  []bool // parallel to Products
+}
+
+// auditSavings groups near-identical products (same medicine type, route and dose frequency) and
+// flags the ones with far less savings program coverage than their peers, which often means data is
+// missing. it's advisory only and never fails the build.
+func auditSavings(products []medcatalog.Product) []savingsAuditGroup {
+	groups := map[string][]medcatalog.Product{}
+	keys := []string{}
+	for _, p := range products {
+		key := strings.Join([]string{string(p.MedicineType), string(p.AdminRoute), string(p.DoseFrequency)}, " / ")
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], p)
+	}
+	slices.Sort(keys)
+
+	audit := []savingsAuditGroup{}
+	for _, key := range keys {
+		members := groups[key]
+		if len(members) < 2 {
+			continue
+		}
+		most := 0
+		for _, p := range members {
+			most = max(most, len(p.Savings))
+		}
+		group := savingsAuditGroup{Key: key, Products: members, Outlier: make([]bool, len(members))}
+		for i, p := range members {
+			group.Outlier[i] = most-len(p.Savings) >= savingsOutlierGap
+		}
+		audit = append(audit, group)
+	}
+	return audit
+}
+
+func printSavingsAudit(audit []savingsAuditGroup) {
+	fmt.Println("savings audit (advisory):")
+	for _, g := range audit {
+		fmt.Println("  " + g.Key)
+		for i, p := range g.Products {
+			flag := ""
+			if g.Outlier[i] {
+				flag = " <- review, far fewer savings programs than similar products"
+			}
+			fmt.Printf("    %s: %d savings program(s)%s\n", p.BrandName, len(p.Savings), flag)
+		}
+	}
+}
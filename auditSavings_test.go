@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+func TestAuditSavingsOutlier(t *testing.T) {
+	programs := make([]medcatalog.SavingsInfo, 3)
+	weekly := func(brandName string, savings int) medcatalog.Product {
+		return medcatalog.Product{BrandName: brandName, MedicineType: "GLP-1", AdminRoute: "Subcutaneous Injection",
+			DoseFrequency: "Once Weekly", Savings: programs[:savings]}
+	}
+	products := []medcatalog.Product{
+		weekly("Glucozen", 3),
+		weekly("Sparsely", 0),
+		weekly("Semaweek", 2),
+		// alone in its group, so there's nothing to compare it with
+		{BrandName: "Sugarbane", MedicineType: "SGLT-2", AdminRoute: "Oral Tablet", DoseFrequency: "Once Daily"},
+	}
+
+	audit := auditSavings(products)
+	if len(audit) != 1 {
+		t.Fatalf("auditSavings() = %d group(s), want only the weekly GLP-1 injectables", len(audit))
+	}
+	for i, p := range audit[0].Products {
+		if want := p.BrandName == "Sparsely"; audit[0].Outlier[i] != want {
+			t.Errorf("%s with %d savings program(s) flagged as an outlier = %t, want %t",
+				p.BrandName, len(p.Savings), audit[0].Outlier[i], want)
+		}
+	}
+}
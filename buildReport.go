@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// buildReportFile is written to the output directory with -report
+const buildReportFile = "build-report.json"
+
+// buildReport is a snapshot of the catalog's health after a render, for the dashboard
+type buildReport struct {
+	GeneratedAt         time.Time            `json:"generated_at"`
+	buildInfo                                // version, commit and build_date
+	Products            int                  `json:"products"`
+	ByMedicineType      map[string]int       `json:"by_medicine_type"`
+	WithFDALabel        int                  `json:"with_fda_label"`
+	LabelsNeedingUpdate int                  `json:"labels_needing_update"`
+	FDALookup           *fdaLookupReport     `json:"fda_lookup,omitempty"` // missing when the FDA check didn't run
+	Warnings            []buildReportWarning `json:"warnings"`
+}
+
+// fdaLookupReport is what the FDA label lookup cost, times are in seconds
+type fdaLookupReport struct {
+	Lookups              int     `json:"lookups"`
+	CacheHits            int     `json:"cache_hits"`
+	CacheMisses          int     `json:"cache_misses"`
+	Requests             int     `json:"requests"`
+	Retries              int     `json:"retries"`
+	RateLimitWaitSeconds float64 `json:"rate_limit_wait_seconds"`
+	NetworkSeconds       float64 `json:"network_seconds"`
+	WallSeconds          float64 `json:"wall_seconds"`
+}
+
+type buildReportWarning struct {
+	BrandName  string `json:"brand_name"`
+	SourceFile string `json:"source_file"`
+	Rule       string `json:"rule"`
+	Message    string `json:"message"`
+}
+
+// renderBuildReport writes build-report.json with counts for the rendered products and the warnings
+// printed during the run. fdaStats is nil when the FDA check didn't run
+func renderBuildReport(w *artifactWriter, products []medcatalog.Product, warnings []buildReportWarning, fdaStats *medcatalog.FDALookupStats) error {
+	report := buildReport{
+		GeneratedAt:    time.Now().UTC(),
+		buildInfo:      currentBuild(),
+		Products:       len(products),
+		ByMedicineType: map[string]int{},
+		Warnings:       warnings,
+	}
+	if fdaStats != nil {
+		report.FDALookup = &fdaLookupReport{
+			Lookups:              fdaStats.Lookups,
+			CacheHits:            fdaStats.CacheHits,
+			CacheMisses:          fdaStats.CacheMisses,
+			Requests:             fdaStats.Requests,
+			Retries:              fdaStats.Retries,
+			RateLimitWaitSeconds: fdaStats.RateLimitWait.Seconds(),
+			NetworkSeconds:       fdaStats.NetworkTime.Seconds(),
+			WallSeconds:          fdaStats.WallTime.Seconds(),
+		}
+	}
+	if report.Warnings == nil {
+		report.Warnings = []buildReportWarning{}
+	}
+	for _, p := range products {
+		report.ByMedicineType[string(p.MedicineType)]++
+		if p.FDALabelFile != "" {
+			report.WithFDALabel++
+		}
+		if p.FDALabelNeedsUpdate {
+			report.LabelsNeedingUpdate++
+		}
+	}
+	content, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed encoding "+buildReportFile), err)
+	}
+	return w.Write(buildReportFile, append(content, '\n'))
+}
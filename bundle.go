@@ -0,0 +1,108 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// bundleModTime is stamped on every entry so the same output always produces the same archive
+var bundleModTime = time.Unix(0, 0).UTC()
+
+// writeBundle packages everything under srcDir into a gzipped tarball at dest. entries are in lexical
+// order with fixed timestamps, owners and modes so bundles of identical output are byte for byte equal.
+func writeBundle(srcDir string, dest string) error {
+	f, err := os.Create(dest)
+	if err != nil {
+		return errors.Join(errors.New("failed creating bundle "+dest), err)
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f) // the gzip header name and time are left empty for reproducibility
+	tw := tar.NewWriter(gz)
+	count := 0
+	// WalkDir visits entries in lexical order
+	err = filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		hdr := &tar.Header{
+			Name:    filepath.ToSlash(rel),
+			ModTime: bundleModTime,
+			Format:  tar.FormatPAX,
+		}
+		if d.IsDir() {
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			hdr.Mode = 0o755
+			return tw.WriteHeader(hdr)
+		}
+		if !d.Type().IsRegular() {
+			return fmt.Errorf("can't bundle %s, only regular files and directories are supported", path)
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		hdr.Typeflag = tar.TypeReg
+		hdr.Mode = 0o644
+		hdr.Size = info.Size()
+		if err = tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		src, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer src.Close()
+		if _, err = io.Copy(tw, src); err != nil {
+			return err
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return errors.Join(errors.New("failed adding files to bundle "+dest), err)
+	}
+	if err = tw.Close(); err != nil {
+		return errors.Join(errors.New("failed finishing tar for bundle "+dest), err)
+	}
+	if err = gz.Close(); err != nil {
+		return errors.Join(errors.New("failed finishing gzip for bundle "+dest), err)
+	}
+	fmt.Printf("bundled %d file(s) from %s into %s\n", count, srcDir, dest)
+	return nil
+}
+
+// copyDir copies the regular files under srcDir into dstDir, used to stage the static files when the
+// output only goes into a bundle
+func copyDir(srcDir string, dstDir string) error {
+	return filepath.WalkDir(srcDir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(srcDir, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dstDir, rel)
+		if d.IsDir() {
+			return os.MkdirAll(target, 0o755)
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, content, 0o644)
+	})
+}
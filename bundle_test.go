@@ -0,0 +1,68 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestBundleReproducible(t *testing.T) {
+	bundles := []string{}
+	for range 2 {
+		bundle := filepath.Join(t.TempDir(), "site.tar.gz")
+		out, code := runMain(t, nil, "-catalog", "testdata/catalog", "-out", t.TempDir(), "-skip-update-check",
+			"-emit-widget", "-pdf", "-bundle", bundle)
+		if code != 0 {
+			t.Fatalf("exit code = %d, want 0\n%s", code, out)
+		}
+		bundles = append(bundles, bundle)
+	}
+	first, err := os.ReadFile(bundles[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := os.ReadFile(bundles[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("bundles of two renders of the same catalog differ")
+	}
+
+	names := bundleEntries(t, first)
+	for _, want := range []string{"index.html", "widget.html", productPagesDir + "glucozen.html", productPagesDir + "sugarbane.html"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("bundle is missing %s, it has %v", want, names)
+		}
+	}
+	if !slices.ContainsFunc(names, func(n string) bool { return strings.HasPrefix(n, handoutsDir) && strings.HasSuffix(n, ".pdf") }) {
+		t.Errorf("bundle has no handouts, it has %v", names)
+	}
+}
+
+// bundleEntries lists the names in a gzipped tarball
+func bundleEntries(t *testing.T, bundle []byte) []string {
+	t.Helper()
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	if err != nil {
+		t.Fatalf("bundle isn't gzipped: %v", err)
+	}
+	tr := tar.NewReader(gz)
+	names := []string{}
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return names
+		} else if err != nil {
+			t.Fatalf("bundle isn't a tarball: %v", err)
+		}
+		names = append(names, hdr.Name)
+	}
+}
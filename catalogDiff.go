@@ -0,0 +1,223 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// productDiff is how one product changed between two catalogs
+type productDiff struct {
+	BrandName      string
+	Fields         []fieldChange
+	SavingsAdded   []string
+	SavingsRemoved []string
+	// SavingsChanged is keyed by the program's description
+	SavingsChanged map[string][]fieldChange
+}
+
+// fieldChange is one JSON field that differs, nested fields are dotted (eligibility.cash_pay)
+type fieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+type catalogDiff struct {
+	Added   []medcatalog.Product
+	Removed []medcatalog.Product
+	Changed []productDiff
+}
+
+// diffCatalogDirs loads both catalog directories and compares them
+func diffCatalogDirs(oldDir, newDir string) (catalogDiff, error) {
+	oldProducts, err := medcatalog.Load(oldDir)
+	if err != nil {
+		return catalogDiff{}, errors.Join(errors.New("failed loading "+oldDir), err)
+	}
+	newProducts, err := medcatalog.Load(newDir)
+	if err != nil {
+		return catalogDiff{}, errors.Join(errors.New("failed loading "+newDir), err)
+	}
+	return diffCatalogs(oldProducts, newProducts)
+}
+
+// diffCatalogs matches products by slug (which defaults to the brand name) and savings programs by
+// type and description, so an edited description shows up as one program removed and one added
+func diffCatalogs(oldProducts, newProducts []medcatalog.Product) (catalogDiff, error) {
+	d := catalogDiff{}
+	oldBySlug := map[string]medcatalog.Product{}
+	for _, p := range oldProducts {
+		oldBySlug[p.Slug] = p
+	}
+	newSlugs := map[string]bool{}
+	for _, p := range newProducts {
+		newSlugs[p.Slug] = true
+		old, ok := oldBySlug[p.Slug]
+		if !ok {
+			d.Added = append(d.Added, p)
+			continue
+		}
+		pd, err := diffProduct(old, p)
+		if err != nil {
+			return d, err
+		}
+		if len(pd.Fields) > 0 || len(pd.SavingsAdded) > 0 || len(pd.SavingsRemoved) > 0 || len(pd.SavingsChanged) > 0 {
+			d.Changed = append(d.Changed, pd)
+		}
+	}
+	for _, p := range oldProducts {
+		if !newSlugs[p.Slug] {
+			d.Removed = append(d.Removed, p)
+		}
+	}
+	return d, nil
+}
+
+func diffProduct(old, new medcatalog.Product) (productDiff, error) {
+	pd := productDiff{BrandName: new.BrandName, SavingsChanged: map[string][]fieldChange{}}
+	oldSavings, newSavings := old.Savings, new.Savings
+	old.Savings, new.Savings = nil, nil
+	var err error
+	if pd.Fields, err = diffJSON(old, new); err != nil {
+		return pd, err
+	}
+
+	oldByKey := map[string]int{}
+	for i, s := range oldSavings {
+		oldByKey[string(s.Type)+"\x00"+s.Description] = i
+	}
+	matched := map[int]bool{}
+	for _, s := range newSavings {
+		i, ok := oldByKey[string(s.Type)+"\x00"+s.Description]
+		if !ok {
+			pd.SavingsAdded = append(pd.SavingsAdded, fmt.Sprintf("%s '%s'", s.Type, s.Description))
+			continue
+		}
+		matched[i] = true
+		changes, err := diffJSON(oldSavings[i], s)
+		if err != nil {
+			return pd, err
+		}
+		if len(changes) > 0 {
+			pd.SavingsChanged[s.Description] = changes
+		}
+	}
+	for i, s := range oldSavings {
+		if !matched[i] {
+			pd.SavingsRemoved = append(pd.SavingsRemoved, fmt.Sprintf("%s '%s'", s.Type, s.Description))
+		}
+	}
+	return pd, nil
+}
+
+// diffJSON compares two values by their catalog JSON, the way editors see them
+func diffJSON(old, new any) ([]fieldChange, error) {
+	oldFields, err := flattenJSON(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flattenJSON(new)
+	if err != nil {
+		return nil, err
+	}
+	changes := []fieldChange{}
+	for _, field := range slices.Sorted(maps.Keys(oldFields)) {
+		if oldFields[field] != newFields[field] {
+			changes = append(changes, fieldChange{Field: field, Old: oldFields[field], New: newFields[field]})
+		}
+	}
+	for _, field := range slices.Sorted(maps.Keys(newFields)) {
+		if _, ok := oldFields[field]; !ok {
+			changes = append(changes, fieldChange{Field: field, New: newFields[field]})
+		}
+	}
+	return changes, nil
+}
+
+// flattenJSON maps each leaf field (objects are dotted, arrays are compared whole) to its JSON
+func flattenJSON(v any) (map[string]string, error) {
+	content, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed encoding product for the diff"), err)
+	}
+	var decoded map[string]any
+	if err = json.Unmarshal(content, &decoded); err != nil {
+		return nil, errors.Join(errors.New("failed decoding product for the diff"), err)
+	}
+	fields := map[string]string{}
+	var flatten func(prefix string, m map[string]any)
+	flatten = func(prefix string, m map[string]any) {
+		for k, v := range m {
+			if nested, ok := v.(map[string]any); ok {
+				flatten(prefix+k+".", nested)
+				continue
+			}
+			encoded, _ := json.Marshal(v)
+			fields[prefix+k] = string(encoded)
+		}
+	}
+	flatten("", decoded)
+	return fields, nil
+}
+
+// printCatalogDiff writes the summary with FDA label date changes listed first for each product
+func printCatalogDiff(d catalogDiff) {
+	for _, p := range d.Added {
+		fmt.Printf("added: %s (%s)\n", p.BrandName, p.SourceFile())
+	}
+	for _, p := range d.Removed {
+		fmt.Printf("removed: %s (%s)\n", p.BrandName, p.SourceFile())
+	}
+	fdaDates := 0
+	for _, pd := range d.Changed {
+		fmt.Printf("changed: %s\n", pd.BrandName)
+		fields := slices.Clone(pd.Fields)
+		slices.SortStableFunc(fields, func(a, b fieldChange) int {
+			return strings.Compare(fieldOrder(a.Field), fieldOrder(b.Field))
+		})
+		for _, c := range fields {
+			if c.Field == "fda_label_file_updated" {
+				fdaDates++
+			}
+			fmt.Printf("  %s\n", c)
+		}
+		for _, s := range pd.SavingsAdded {
+			fmt.Printf("  savings added: %s\n", s)
+		}
+		for _, s := range pd.SavingsRemoved {
+			fmt.Printf("  savings removed: %s\n", s)
+		}
+		for _, description := range slices.Sorted(maps.Keys(pd.SavingsChanged)) {
+			fmt.Printf("  savings changed '%s':\n", description)
+			for _, c := range pd.SavingsChanged[description] {
+				fmt.Printf("    %s\n", c)
+			}
+		}
+	}
+	fmt.Printf("%d added, %d removed, %d changed (%d FDA label date change(s))\n",
+		len(d.Added), len(d.Removed), len(d.Changed), fdaDates)
+}
+
+// fieldOrder puts the FDA label date first, the rest stay alphabetical
+func fieldOrder(field string) string {
+	if field == "fda_label_file_updated" {
+		return ""
+	}
+	return field
+}
+
+func (c fieldChange) String() string {
+	switch {
+	case c.Old == "":
+		return fmt.Sprintf("%s: set to %s", c.Field, c.New)
+	case c.New == "":
+		return fmt.Sprintf("%s: removed (was %s)", c.Field, c.Old)
+	}
+	return fmt.Sprintf("%s: %s -> %s", c.Field, c.Old, c.New)
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// renderColorsCSS writes colors.css with a gradient class for each defined color
+func renderColorsCSS(w *artifactWriter) error {
+	var buf bytes.Buffer
+	buf.WriteString("/* generated from ColorClasses in medcatalog/colors.go, edit the colors there */\n")
+	for _, c := range medcatalog.ColorClasses {
+		fmt.Fprintf(&buf, ".%s { background: linear-gradient(135deg, %s, %s); }\n", c.ClassName(), c.From, c.To)
+	}
+	return w.Write("colors.css", buf.Bytes())
+}
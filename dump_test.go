@@ -0,0 +1,29 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDumpProductsIncludesDerivedFields(t *testing.T) {
+	dump := filepath.Join(t.TempDir(), "dump.json")
+	if err := dumpProducts(dump, loadTestCatalog(t)); err != nil {
+		t.Fatalf("dumpProducts() failed: %v", err)
+	}
+	content, err := os.ReadFile(dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var products []map[string]any
+	if err = json.Unmarshal(content, &products); err != nil {
+		t.Fatalf("dump isn't a JSON list: %v", err)
+	}
+	if len(products) != 2 {
+		t.Fatalf("dump has %d products, want 2", len(products))
+	}
+	if products[0]["slug"] != "glucozen" || products[0]["color_class"] == nil {
+		t.Errorf("dump is missing the derived slug or color_class: %v", products[0])
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"fmt"
+	"io"
+)
+
+const defaultMaxErrors = 200
+
+// errorCollector accumulates findings across the whole catalog so they can be reported together.
+// once max findings are held any further ones are only counted, keeping output readable on badly
+// broken catalogs. A max of 0 or less means no cap.
+type errorCollector struct {
+	max     int
+	errs    []error
+	dropped int
+}
+
+func newErrorCollector(max int) *errorCollector {
+	return &errorCollector{max: max}
+}
+
+func (c *errorCollector) Add(err error) {
+	if err == nil {
+		return
+	}
+	if c.max > 0 && len(c.errs) >= c.max {
+		c.dropped++
+		return
+	}
+	c.errs = append(c.errs, err)
+}
+
+// Count is the total number of findings, including the ones dropped past the cap
+func (c *errorCollector) Count() int {
+	return len(c.errs) + c.dropped
+}
+
+// Print writes each finding held on its own line, and how many more were dropped past the cap
+func (c *errorCollector) Print(w io.Writer) {
+	for _, err := range c.errs {
+		fmt.Fprintln(w, err)
+	}
+	if c.dropped > 0 {
+		fmt.Fprintf(w, "... and %d more\n", c.dropped)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestErrorCollectorCap(t *testing.T) {
+	c := newErrorCollector(3)
+	for i := range 5 {
+		c.Add(fmt.Errorf("finding %d", i))
+	}
+	c.Add(nil)
+	if c.Count() != 5 {
+		t.Errorf("Count() = %d, want 5 including the dropped findings", c.Count())
+	}
+	var out bytes.Buffer
+	c.Print(&out)
+	want := "finding 0\nfinding 1\nfinding 2\n... and 2 more\n"
+	if out.String() != want {
+		t.Errorf("Print() wrote %q, want %q", out.String(), want)
+	}
+}
+
+func TestErrorCollectorNoCap(t *testing.T) {
+	c := newErrorCollector(0)
+	for i := range 250 {
+		c.Add(fmt.Errorf("finding %d", i))
+	}
+	var out bytes.Buffer
+	c.Print(&out)
+	if c.Count() != 250 || strings.Contains(out.String(), "more") {
+		t.Errorf("with no cap Count() = %d and Print() mentions dropped findings: %v", c.Count(), strings.Contains(out.String(), "more"))
+	}
+}
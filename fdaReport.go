@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// fdaReportRow is one product's line in the -fda-report table
+type fdaReportRow struct {
+	BrandName   string
+	Recorded    string
+	Effective   string
+	NeedsUpdate bool
+}
+
+// fdaLabelReport runs the FDA label recency check against a copy of the products so the catalog
+// being rendered is left alone, and returns a row for each product that was looked up
+func fdaLabelReport(list medcatalog.ProductList, opts medcatalog.FDALookupOptions) ([]fdaReportRow, error) {
+	checked := slices.Clone(list)
+	stats, err := medcatalog.EnrichFromFDA(checked, opts)
+	if err != nil {
+		return nil, err
+	}
+	fmt.Println(stats)
+	rows := []fdaReportRow{}
+	for _, p := range checked {
+		if p.SkipFDALookup() {
+			continue
+		}
+		row := fdaReportRow{BrandName: p.BrandName, Recorded: p.FDALabelUpdated, Effective: p.FDALabelLatest,
+			NeedsUpdate: p.FDALabelNeedsUpdate}
+		if row.Recorded == "" {
+			row.Recorded = "none"
+		}
+		if row.Effective == "" {
+			row.Effective = "not found"
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func printFDALabelReport(rows []fdaReportRow) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BRAND\tRECORDED\tFDA EFFECTIVE\tNEEDS UPDATE")
+	stale := 0
+	for _, r := range rows {
+		needsUpdate := "no"
+		if r.NeedsUpdate {
+			needsUpdate = "yes"
+			stale++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", r.BrandName, r.Recorded, r.Effective, needsUpdate)
+	}
+	tw.Flush()
+	fmt.Printf("%d of %d label(s) need updating\n", stale, len(rows))
+}
@@ -2,4 +2,10 @@ module github.com/samiam2013/pugnarehealth
 
 go 1.24.0
 
-require golang.org/x/time v0.14.0
+require (
+	github.com/jung-kurt/gofpdf v1.16.2
+	github.com/santhosh-tekuri/jsonschema/v6 v6.0.2
+	golang.org/x/text v0.14.0
+	golang.org/x/time v0.14.0
+	gopkg.in/yaml.v3 v3.0.1
+)
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jung-kurt/gofpdf"
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// handouts go in this directory of the output, one per medicine type
+const handoutsDir = "handouts/"
+
+// fixed creation and modification dates keep the PDF bytes the same for the same catalog, so
+// unchanged handouts aren't rewritten and bundles are reproducible
+var handoutCreationDate = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// renderHandouts writes a printable handout for each medicine type to handouts/<medicine-type>.pdf,
+// listing every product's ingredient, dose frequency and savings contacts, with the FDA label links
+// as footnotes. device types (CGMs, insulin pumps) get one too, they have no FDA label or dose
+// frequency so those are left out. expired savings programs are left out
+func renderHandouts(w *artifactWriter, products []medcatalog.Product) error {
+	for _, group := range groupByMedicineType(products) {
+		name := handoutsDir + medcatalog.Slugify(group.Type) + ".pdf"
+		err := w.WriteFunc(name, func(buf *bytes.Buffer) error {
+			return writeHandout(buf, group)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeHandout(buf *bytes.Buffer, group productGroup) error {
+	pdf := gofpdf.New("P", "mm", "Letter", "")
+	pdf.SetCreationDate(handoutCreationDate)
+	pdf.SetModificationDate(handoutCreationDate)
+	pdf.SetCatalogSort(true)
+	pdf.SetTitle(group.Type+" savings programs", true)
+	pdf.SetMargins(18, 18, 18)
+	pdf.SetAutoPageBreak(true, 18)
+	// the core fonts are cp1252, this maps é, — and ® into it
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-12)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(0, 4, tr(fmt.Sprintf("pugnare.health - %s - page %d", group.Type, pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 18)
+	pdf.MultiCell(0, 8, tr(group.Type+" savings programs"), "", "L", false)
+	pdf.SetFont("Helvetica", "", 9)
+	pdf.MultiCell(0, 5, tr("Programs change often, confirm the terms by phone or on the program's website before enrolling."), "", "L", false)
+	pdf.Ln(4)
+
+	footnotes := []string{}
+	for _, p := range group.Products {
+		pdf.SetFont("Helvetica", "B", 13)
+		heading := p.BrandName
+		if link, ok := handoutLabelLink(p); ok {
+			footnotes = append(footnotes, fmt.Sprintf("%s FDA label: %s", p.BrandName, link))
+			heading += fmt.Sprintf(" [%d]", len(footnotes))
+		}
+		pdf.MultiCell(0, 6, tr(heading), "", "L", false)
+		pdf.SetFont("Helvetica", "", 10)
+		details := []string{p.IngredientName}
+		if p.DoseFrequency != "" && !p.IsDevice() {
+			details = append(details, "dosed "+strings.ToLower(string(p.DoseFrequency)))
+		}
+		details = append(details, string(p.AdminRoute))
+		pdf.MultiCell(0, 5, tr(strings.Join(details, " - ")), "", "L", false)
+
+		for _, s := range p.Savings {
+			if s.Expired {
+				continue
+			}
+			pdf.Ln(1)
+			pdf.SetX(24)
+			pdf.SetFont("Helvetica", "B", 10)
+			pdf.MultiCell(0, 5, tr(string(s.Type)), "", "L", false)
+			pdf.SetX(24)
+			pdf.SetFont("Helvetica", "", 10)
+			pdf.MultiCell(0, 5, tr(s.Description), "", "L", false)
+			lines := []string{}
+			if s.Phone != "" {
+				lines = append(lines, "Phone: "+s.Phone)
+			}
+			if s.Link != "" {
+				lines = append(lines, "Website: "+s.Link)
+			}
+			eligible := []string{}
+			if s.Eligibility.PrivateInsurance {
+				eligible = append(eligible, "private insurance")
+			}
+			if s.Eligibility.GovernmentInsurance {
+				eligible = append(eligible, "government insurance")
+			}
+			if s.Eligibility.CashPay {
+				eligible = append(eligible, "cash pay")
+			}
+			if len(eligible) > 0 {
+				lines = append(lines, "For: "+strings.Join(eligible, ", "))
+			}
+			if limit := s.Eligibility.IncomeLimitFPLPercent; limit > 0 {
+				lines = append(lines, fmt.Sprintf("Income limit: %d%% of the federal poverty level", limit))
+			}
+			if len(s.Eligibility.OtherCriteria) > 0 {
+				lines = append(lines, "Requires: "+strings.Join(s.Eligibility.OtherCriteria, "; "))
+			}
+			if s.Expires != "" {
+				lines = append(lines, "Ends "+formatDate(s.Expires))
+			}
+			for _, line := range lines {
+				pdf.SetX(24)
+				pdf.MultiCell(0, 5, tr(line), "", "L", false)
+			}
+		}
+		pdf.Ln(4)
+	}
+
+	if len(footnotes) > 0 {
+		pdf.SetFont("Helvetica", "", 8)
+		for i, note := range footnotes {
+			pdf.MultiCell(0, 4, tr(fmt.Sprintf("[%d] %s", i+1, note)), "", "L", false)
+		}
+	}
+	if err := pdf.Output(buf); err != nil {
+		return errors.Join(errors.New("failed writing the "+group.Type+" handout"), err)
+	}
+	return nil
+}
+
+// handoutLabelLink is the FDA's own https URL for the product's label. a handout is printed, so it
+// never points at the copy -download-labels keeps under the site (FDALabelLocalFile) or anything
+// else that isn't a public URL. devices have no FDA label
+func handoutLabelLink(p medcatalog.Product) (string, bool) {
+	if p.IsDevice() || p.FDALabelFile == "" {
+		return "", false
+	}
+	u, err := url.Parse(p.FDALabelFile)
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return "", false
+	}
+	return p.FDALabelFile, true
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+func TestRenderHandouts(t *testing.T) {
+	products := loadTestCatalog(t)
+	dir := t.TempDir()
+	if err := renderHandouts(newArtifactWriter(dir), products); err != nil {
+		t.Fatalf("renderHandouts() failed: %v", err)
+	}
+	first, err := os.ReadFile(filepath.Join(dir, handoutsDir, "glp-1.pdf"))
+	if err != nil {
+		t.Fatalf("no GLP-1 handout: %v", err)
+	}
+	if !bytes.HasPrefix(first, []byte("%PDF-")) {
+		t.Fatalf("GLP-1 handout starts with %q, want a PDF", first[:min(len(first), 8)])
+	}
+
+	// the same catalog renders the same bytes, so unchanged handouts aren't rewritten
+	w := newArtifactWriter(dir)
+	if err := renderHandouts(w, products); err != nil {
+		t.Fatalf("second renderHandouts() failed: %v", err)
+	}
+	if w.written != 0 || w.skipped != 2 {
+		t.Errorf("re-rendering the handouts wrote %d and skipped %d, want 0 and 2", w.written, w.skipped)
+	}
+	second, err := os.ReadFile(filepath.Join(dir, handoutsDir, "glp-1.pdf"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first, second) {
+		t.Error("GLP-1 handout changed between two renders of the same catalog")
+	}
+}
+
+func TestHandoutLabelLink(t *testing.T) {
+	label := "https://www.accessdata.fda.gov/drugsatfda_docs/label/2025/000001s001lbl.pdf"
+	tests := []struct {
+		name string
+		p    medcatalog.Product
+		want string
+	}{
+		{"public label", medcatalog.Product{MedicineType: "GLP-1", FDALabelFile: label}, label},
+		{"downloaded label", medcatalog.Product{MedicineType: "GLP-1", FDALabelFile: label, FDALabelLocalFile: "labels/glucozen.pdf"}, label},
+		{"local path", medcatalog.Product{MedicineType: "GLP-1", FDALabelFile: "labels/glucozen.pdf"}, ""},
+		{"no label", medcatalog.Product{MedicineType: "GLP-1"}, ""},
+		{"device", medcatalog.Product{MedicineType: "CGM", FDALabelFile: label}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := handoutLabelLink(tt.p)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("handoutLabelLink() = %q, %t, want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
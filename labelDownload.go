@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+	"golang.org/x/time/rate"
+)
+
+// downloaded labels go in this directory of the output, the manifest remembers what was downloaded
+// so unchanged labels aren't fetched again
+const (
+	labelsDir          = "labels/"
+	labelManifestFile  = ".render-cache/labels.json"
+	labelDownloadLimit = 50 << 20
+)
+
+// labelManifestEntry is what the FDA's server said about a label when it was downloaded
+type labelManifestEntry struct {
+	URL           string `json:"url"`
+	ETag          string `json:"etag,omitempty"`
+	ContentLength int64  `json:"content_length"`
+}
+
+// failedLabel is an FDA label file that couldn't be downloaded, its product keeps linking to the FDA
+type failedLabel struct {
+	BrandName  string
+	SourceFile string
+	URL        string
+	Err        error
+}
+
+// downloadLabels saves each product's FDA label PDF to labels/<slug>.pdf in outputDir and points
+// FDALabelLocalFile at it. labels whose ETag or size haven't changed since the last download are
+// left alone. a label that can't be downloaded is returned rather than failing the build.
+func downloadLabels(ctx context.Context, outputDir string, products []medcatalog.Product) ([]failedLabel, error) {
+	manifest, err := readLabelManifest()
+	if err != nil {
+		return nil, err
+	}
+	c := &http.Client{Timeout: linkCheckTimeout}
+	l := rate.NewLimiter(rate.Every(linkCheckInterval), 1)
+	failed := []failedLabel{}
+	for i, p := range products {
+		if p.FDALabelFile == "" {
+			continue
+		}
+		name := labelsDir + p.Slug + ".pdf"
+		path := filepath.Join(outputDir, name)
+		entry, err := downloadLabel(ctx, c, l, p.FDALabelFile, path, manifest[p.Slug])
+		if err != nil {
+			failed = append(failed, failedLabel{BrandName: p.BrandName, SourceFile: p.SourceFile(), URL: p.FDALabelFile, Err: err})
+			continue
+		}
+		manifest[p.Slug] = entry
+		products[i].FDALabelLocalFile = name
+	}
+	if err = writeLabelManifest(manifest); err != nil {
+		return failed, err
+	}
+	return failed, nil
+}
+
+// downloadLabel fetches the label into path unless the copy already there matches what the server has
+func downloadLabel(ctx context.Context, c *http.Client, l *rate.Limiter, link, path string, previous labelManifestEntry) (labelManifestEntry, error) {
+	if info, err := os.Stat(path); err == nil && previous.URL == link {
+		current, err := labelHeaders(ctx, c, l, link)
+		if err == nil && labelUnchanged(previous, current, info.Size()) {
+			fmt.Println(path + " unchanged.")
+			return previous, nil
+		}
+	}
+
+	if err := l.Wait(ctx); err != nil {
+		return labelManifestEntry{}, errors.Join(errors.New("rate limiter wait failed"), err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, link, nil)
+	if err != nil {
+		return labelManifestEntry{}, errors.Join(errors.New("failed creating request"), err)
+	}
+	req.Header.Set("User-Agent", "pugnare.health/1.0")
+	resp, err := c.Do(req)
+	if err != nil {
+		return labelManifestEntry{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return labelManifestEntry{}, fmt.Errorf("status %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	if mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type")); mediaType != "application/pdf" {
+		return labelManifestEntry{}, fmt.Errorf("content type is %q, not application/pdf", resp.Header.Get("Content-Type"))
+	}
+	content, err := io.ReadAll(io.LimitReader(resp.Body, labelDownloadLimit+1))
+	if err != nil {
+		return labelManifestEntry{}, errors.Join(errors.New("failed reading label"), err)
+	}
+	switch {
+	case len(content) == 0:
+		return labelManifestEntry{}, errors.New("label is empty")
+	case len(content) > labelDownloadLimit:
+		return labelManifestEntry{}, fmt.Errorf("label is bigger than %d MB", labelDownloadLimit>>20)
+	}
+
+	if err = os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return labelManifestEntry{}, errors.Join(errors.New("failed creating directory "+filepath.Dir(path)), err)
+	}
+	if err = os.WriteFile(path, content, 0o644); err != nil {
+		return labelManifestEntry{}, errors.Join(errors.New("failed writing "+path), err)
+	}
+	fmt.Println(path + " downloaded.")
+	return labelManifestEntry{URL: link, ETag: resp.Header.Get("ETag"), ContentLength: int64(len(content))}, nil
+}
+
+// labelHeaders asks for the label's ETag and size without downloading it
+func labelHeaders(ctx context.Context, c *http.Client, l *rate.Limiter, link string) (labelManifestEntry, error) {
+	if err := l.Wait(ctx); err != nil {
+		return labelManifestEntry{}, errors.Join(errors.New("rate limiter wait failed"), err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, link, nil)
+	if err != nil {
+		return labelManifestEntry{}, errors.Join(errors.New("failed creating request"), err)
+	}
+	req.Header.Set("User-Agent", "pugnare.health/1.0")
+	resp, err := c.Do(req)
+	if err != nil {
+		return labelManifestEntry{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return labelManifestEntry{}, fmt.Errorf("status %d %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+	}
+	length, _ := strconv.ParseInt(resp.Header.Get("Content-Length"), 10, 64)
+	return labelManifestEntry{URL: link, ETag: resp.Header.Get("ETag"), ContentLength: length}, nil
+}
+
+// labelUnchanged compares by ETag when the server sends one, otherwise by size
+func labelUnchanged(previous, current labelManifestEntry, localSize int64) bool {
+	if current.ETag != "" {
+		return current.ETag == previous.ETag
+	}
+	return current.ContentLength > 0 && current.ContentLength == localSize
+}
+
+func readLabelManifest() (map[string]labelManifestEntry, error) {
+	manifest := map[string]labelManifestEntry{}
+	content, err := os.ReadFile(repoPath + labelManifestFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return manifest, nil
+	} else if err != nil {
+		return nil, errors.Join(errors.New("failed reading "+labelManifestFile), err)
+	}
+	if err = json.Unmarshal(content, &manifest); err != nil {
+		// a corrupt manifest just means everything is downloaded again
+		return map[string]labelManifestEntry{}, nil
+	}
+	return manifest, nil
+}
+
+func writeLabelManifest(manifest map[string]labelManifestEntry) error {
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed encoding "+labelManifestFile), err)
+	}
+	if err = os.MkdirAll(filepath.Dir(repoPath+labelManifestFile), 0o755); err != nil {
+		return errors.Join(errors.New("failed creating directory for "+labelManifestFile), err)
+	}
+	if err = os.WriteFile(repoPath+labelManifestFile, content, 0o644); err != nil {
+		return errors.Join(errors.New("failed writing "+labelManifestFile), err)
+	}
+	return nil
+}
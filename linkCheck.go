@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+	"golang.org/x/time/rate"
+)
+
+// how long to wait on a single link, how often to hit any one host and how many links to check at once
+const (
+	linkCheckTimeout  = 10 * time.Second
+	linkCheckInterval = 250 * time.Millisecond
+	linkCheckWorkers  = 8
+)
+
+// linkRef is a product whose savings programs or FDA label use a link
+type linkRef struct {
+	BrandName  string
+	SourceFile string
+}
+
+func (r linkRef) String() string {
+	return fmt.Sprintf("%s (%s)", r.BrandName, r.SourceFile)
+}
+
+// brokenLink is a savings or FDA label link that didn't answer with a 2xx, with every product that
+// uses it
+type brokenLink struct {
+	URL      string
+	Err      error
+	Products []linkRef
+}
+
+func (b brokenLink) Error() string {
+	return fmt.Sprintf("link %s isn't reachable: %v", b.URL, b.Err)
+}
+
+// hostLimiters rate limits requests per host, so checking many links on one manufacturer's site
+// doesn't hammer it while links on other hosts go ahead
+type hostLimiters struct {
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func (h *hostLimiters) Wait(ctx context.Context, link string) error {
+	host := link
+	if u, err := url.Parse(link); err == nil {
+		host = u.Host
+	}
+	h.mu.Lock()
+	if h.limiters == nil {
+		h.limiters = map[string]*rate.Limiter{}
+	}
+	l, ok := h.limiters[host]
+	if !ok {
+		l = rate.NewLimiter(rate.Every(linkCheckInterval), 1)
+		h.limiters[host] = l
+	}
+	h.mu.Unlock()
+	return l.Wait(ctx)
+}
+
+// checkLinks requests every savings link and FDA label file once, no matter how many products use
+// it, returning the ones that fail to connect or don't come back with a 2xx status in the order
+// they first appear in the catalog. links are checked concurrently, limited per host. it also returns
+// how many unique links were checked
+func checkLinks(ctx context.Context, products []medcatalog.Product) ([]brokenLink, int) {
+	refs := map[string][]linkRef{}
+	links := []string{}
+	for _, p := range products {
+		productLinks := []string{}
+		for _, s := range p.Savings {
+			if s.Link != "" {
+				productLinks = append(productLinks, s.Link)
+			}
+		}
+		if p.FDALabelFile != "" {
+			productLinks = append(productLinks, p.FDALabelFile)
+		}
+		for _, link := range productLinks {
+			ref := linkRef{BrandName: p.BrandName, SourceFile: p.SourceFile()}
+			if _, ok := refs[link]; !ok {
+				links = append(links, link)
+			} else if slices.Contains(refs[link], ref) {
+				continue
+			}
+			refs[link] = append(refs[link], ref)
+		}
+	}
+
+	c := &http.Client{Timeout: linkCheckTimeout}
+	limiters := &hostLimiters{}
+	results := make([]error, len(links))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range linkCheckWorkers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// each worker writes its own slots, so no locking is needed
+				results[i] = checkLink(ctx, c, limiters, links[i])
+			}
+		}()
+	}
+feed:
+	for i := range links {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	broken := []brokenLink{}
+	for i, link := range links {
+		if results[i] != nil {
+			broken = append(broken, brokenLink{URL: link, Err: results[i], Products: refs[link]})
+		}
+	}
+	return broken, len(links)
+}
+
+// checkLink sends a HEAD request for the link, falling back to GET for servers that don't allow HEAD
+func checkLink(ctx context.Context, c *http.Client, l *hostLimiters, link string) error {
+	status, err := requestLinkStatus(ctx, c, l, http.MethodHead, link)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = requestLinkStatus(ctx, c, l, http.MethodGet, link)
+	}
+	if err != nil {
+		return err
+	}
+	if status < 200 || status > 299 {
+		return fmt.Errorf("status %d %s", status, http.StatusText(status))
+	}
+	return nil
+}
+
+func requestLinkStatus(ctx context.Context, c *http.Client, l *hostLimiters, method, link string) (int, error) {
+	if err := l.Wait(ctx, link); err != nil {
+		return 0, errors.Join(errors.New("rate limiter wait failed"), err)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, link, nil)
+	if err != nil {
+		return 0, errors.Join(errors.New("failed creating request"), err)
+	}
+	req.Header.Set("User-Agent", "pugnare.health/1.0")
+	resp, err := c.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// drain a little of a GET body so the connection can be reused, the content doesn't matter
+	_, _ = io.CopyN(io.Discard, resp.Body, 64<<10)
+	return resp.StatusCode, nil
+}
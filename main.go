@@ -1,6 +1,8 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"flag"
@@ -8,285 +10,756 @@ import (
 	"html/template"
 	"net/url"
 	"os"
-	"regexp"
+	"os/signal"
+	"path/filepath"
 	"slices"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
 )
 
 const repoPath = "./"
 
-// relative to the root of the repo, not the current working directory
-const medCatalogPath = "catalog/"
-
-var medTypeEnum = NewEnum([]string{
-	"CGM",
-	"SGLT-2",
-	"GLP-1",
-	"DPP-4",
-	"Insulin Delivery System",
-	"Insulin",
-})
-
-var adminRouteEnum = NewEnum([]string{
-	"Oral Tablet",
-	"Subcutaneous Injection",
-	"Automatic Applicator",
-	"Tubeless Insulin Pump",
-})
-
-var savingsTypeEnum = NewEnum([]string{
-	"Copay Discount Card",
-	"Patient Assistance Program",
-	"Medicare Prescription Payment Plan",
-	"Free Trial Offer",
-})
+// relative to the root of the repo, where the rendered site is written
+const outputPath = "public/"
 
 func main() {
 	var skipUpdateCheck bool
 	flag.BoolVar(&skipUpdateCheck, "skip-update-check", false, "Render normally but don't check FDA api for label updates")
+	var validateOnly bool
+	flag.BoolVar(&validateOnly, "validate-only", false, "Only load and validate the catalog, no FDA lookups or rendering (no network needed)")
+	var strict bool
+	flag.BoolVar(&strict, "strict", false, "Fail when any product's FDA label is newer than the one recorded in the catalog")
+	var fdaAdvisory bool
+	flag.BoolVar(&fdaAdvisory, "fda-advisory", false, "Treat FDA label lookup errors as warnings and always go on to render")
+	var fdaOpts medcatalog.FDALookupOptions
+	flag.BoolVar(&fdaOpts.OverwriteRxCUIs, "overwrite-rxcuis", false, "Replace RxCUIs set in the catalog with the ones from the FDA label lookup")
+	flag.BoolVar(&fdaOpts.VerifyNDC, "verify-ndc", false, "Fail when a product's NDCs aren't on any of its matching FDA labels")
+	flag.IntVar(&fdaOpts.Workers, "fda-workers", 4, "Number of FDA label lookups to run at once (they share one rate limit)")
+	flag.DurationVar(&fdaOpts.CacheTTL, "fda-cache-ttl", medcatalog.DefaultFDACacheTTL, "Reuse cached FDA API responses younger than this")
+	flag.BoolVar(&fdaOpts.NoCache, "no-cache", false, "Don't read or write the FDA API response cache")
+	flag.StringVar(&fdaOpts.CacheDir, "fda-cache-dir", medcatalog.DefaultFDACacheDir, "Directory to cache FDA API responses in")
+	flag.DurationVar(&fdaOpts.Timeout, "fda-timeout", 15*time.Second, "Timeout for each FDA API request")
+	flag.IntVar(&fdaOpts.MaxRetries, "fda-max-retries", 3, "How many times to retry an FDA API request that got a 429 or 5xx response")
+	flag.BoolVar(&fdaOpts.QuotePhrases, "fda-quote-phrases", true, "Quote multi-word brand names so the FDA search matches the exact phrase")
+	flag.StringVar(&fdaOpts.APIKey, "fda-api-key", "", "OpenFDA API key (defaults to $FDA_API_KEY), raises the limit from 1,000 to 120,000 requests a day "+
+		"so requests start at OpenFDA's 240 a minute instead of one every 2s")
+	var auditSavingsReport bool
+	flag.BoolVar(&auditSavingsReport, "audit-savings", false, "Print an advisory report of similar products with very different savings coverage")
+	var refreshPharmClasses bool
+	flag.BoolVar(&refreshPharmClasses, "refresh-pharm-classes", false, "Re-fetch the pinned snapshot of FDA pharm classes before validating")
+	var summaryOut string
+	flag.StringVar(&summaryOut, "summary-out", "", "Write a JSON summary of the run to this file")
+	var summaryAppend bool
+	flag.BoolVar(&summaryAppend, "summary-append", false, "Append to the -summary-out file instead of overwriting it")
+	var renderDiff bool
+	flag.BoolVar(&renderDiff, "render-diff", false, "Preview which product cards changed since the last -render-diff run without writing the site")
+	var emitWidget bool
+	flag.BoolVar(&emitWidget, "emit-widget", false, "Also render public/widget.html, an embeddable product list for partner sites")
+	var placeholderWhenEmpty bool
+	flag.BoolVar(&placeholderWhenEmpty, "placeholder-when-empty", false, "Render "+placeholderTemplateFile+" instead of the index when the catalog has no products")
+	var bundlePath string
+	flag.StringVar(&bundlePath, "bundle", "", "After rendering, package the output directory into this .tar.gz file")
+	var bundleOnly bool
+	flag.BoolVar(&bundleOnly, "bundle-only", false, "With -bundle, render into a temporary directory so "+outputPath+" is left untouched")
+	var noJSON bool
+	flag.BoolVar(&noJSON, "no-json", false, "Don't write public/products.json, only the HTML")
+	var baseURLFlag string
+	flag.StringVar(&baseURLFlag, "base-url", "", "Public https URL the site is served from, writes public/sitemap.xml when set")
+	var checkLinksFlag bool
+	flag.BoolVar(&checkLinksFlag, "check-links", false, "Request every savings link and FDA label file and warn about any that aren't reachable")
+	var linksFatal bool
+	flag.BoolVar(&linksFatal, "check-links-fatal", false, "With -check-links, fail the build when a link isn't reachable instead of warning")
+	var newProductBrand string
+	flag.StringVar(&newProductBrand, "new-product", "", "Write a template catalog file for this brand name to fill in, then exit")
+	var dumpPath string
+	flag.StringVar(&dumpPath, "dump", "", "Write the products exactly as they are about to be rendered to this file as JSON, for debugging")
+	var maxErrors int
+	flag.IntVar(&maxErrors, "max-errors", defaultMaxErrors, "Stop collecting validation errors after this many (0 for no limit)")
+	var watchMode bool
+	flag.BoolVar(&watchMode, "watch", false, "Re-render whenever the catalog or template changes, with -serve the browser reloads too")
+	var serveSite bool
+	flag.BoolVar(&serveSite, "serve", false, "After rendering, serve the site and a JSON API over HTTP")
+	var serveAddr string
+	flag.StringVar(&serveAddr, "addr", ":8080", "Address to listen on when using -serve")
+	var postRenderCmd string
+	flag.StringVar(&postRenderCmd, "post-render-cmd", "", "Command to run after a successful render, the output directory is passed as the last argument")
+	var fdaReport bool
+	flag.BoolVar(&fdaReport, "fda-report", false, "Print which FDA labels are newer than the catalog records, without rendering or changing anything, then exit")
+	var fdaWriteBack bool
+	flag.BoolVar(&fdaWriteBack, "fda-write-back", false, "Write the FDA's newer label effective dates into fda_label_file_updated in the catalog files")
+	var loadOpts medcatalog.LoadOptions
+	flag.BoolVar(&loadOpts.ContinueOnParseError, "continue-on-parse-error", false, "Skip catalog files that aren't valid JSON or YAML instead of failing, listing them at the end")
+	var failOnWarning bool
+	flag.BoolVar(&failOnWarning, "fail-on-warning", false, "Exit 1 if any warning was reported, after rendering, treating warnings like errors (e.g. in CI)")
+	var requireFDALabel bool
+	flag.BoolVar(&requireFDALabel, "require-fda-label", false, "Fail instead of warning when a drug (not a device) has no fda_label_file")
+	var formatFiles, formatCheck bool
+	flag.BoolVar(&formatFiles, "format", false, "Rewrite the JSON catalog files with canonical key order and indentation, then exit")
+	flag.BoolVar(&formatCheck, "format-check", false, "Like -format but only list the files that aren't formatted, exiting 1 if there are any")
+	var handoutsFlag bool
+	flag.BoolVar(&handoutsFlag, "pdf", false, "Also write a printable handout per medicine type to public/handouts/<medicine-type>.pdf")
+	var diffCatalogsFlag bool
+	flag.BoolVar(&diffCatalogsFlag, "diff", false, "Summarize the products, savings programs and FDA label dates that changed between two catalog directories (-diff old new), then exit")
+	var downloadLabelsFlag bool
+	flag.BoolVar(&downloadLabelsFlag, "download-labels", false, "Download each FDA label PDF to public/labels/ and link the local copy")
+	var a11yCheck bool
+	flag.BoolVar(&a11yCheck, "a11y", false, "After rendering, warn about links without text, images without alt text and savings descriptions that are only a URL")
+	var hideExpired bool
+	flag.BoolVar(&hideExpired, "hide-expired", false, "Leave savings programs past their expires date out of the rendered site instead of marking them expired")
+	var writeReport bool
+	flag.BoolVar(&writeReport, "report", false, "Also write public/build-report.json with product counts and the run's warnings")
+	var showVersion bool
+	flag.BoolVar(&showVersion, "version", false, "Print the version, commit and build date, then exit")
+	var templates siteTemplates
+	flag.Var(&templates, "template", "Render this template to this file in the output directory instead of index.gohtml=index.html, as template=output (repeatable)")
+	var catalogDir string
+	flag.StringVar(&catalogDir, "catalog", medcatalog.DefaultDir, "Directory to read the catalog files from")
+	var outDir string
+	flag.StringVar(&outDir, "out", outputPath, "Directory to render the site into, created if missing (copy the static files from public/ into it)")
+	var sortBy string
+	flag.StringVar(&sortBy, "sort-by", medcatalog.DefaultSortKey, "Order of products without a list_position: brand, ingredient, medicine-type or fda-updated (newest first)")
 	flag.Parse()
+	if len(templates) == 0 {
+		templates = siteTemplates{indexTemplate}
+	}
+	if showVersion {
+		fmt.Println(currentBuild())
+		return
+	}
+	if fdaOpts.APIKey == "" {
+		// read after parsing so the key never shows up as the flag default in -h
+		fdaOpts.APIKey = os.Getenv("FDA_API_KEY")
+	}
+	if _, ok := medcatalog.SortKeys[sortBy]; !ok {
+		fmt.Println("-sort-by must be one of brand, ingredient, medicine-type or fda-updated")
+		os.Exit(1)
+	}
+	if strict && (skipUpdateCheck || validateOnly) {
+		fmt.Println("-strict needs the FDA label check, it can't be used with -skip-update-check or -validate-only")
+		os.Exit(1)
+	}
+	if fdaReport && (skipUpdateCheck || validateOnly) {
+		fmt.Println("-fda-report needs the FDA label check, it can't be used with -skip-update-check or -validate-only")
+		os.Exit(1)
+	}
+	if strict && fdaAdvisory {
+		fmt.Println("-strict fails on outdated FDA labels, it can't be used with -fda-advisory which renders when they can't be checked")
+		os.Exit(1)
+	}
+	if fdaWriteBack && (skipUpdateCheck || validateOnly || strict || fdaReport) {
+		fmt.Println("-fda-write-back needs the FDA label check and can't be used with -skip-update-check, -validate-only, -strict or -fda-report")
+		os.Exit(1)
+	}
+	if diffCatalogsFlag && flag.NArg() != 2 {
+		fmt.Println("-diff needs the old and new catalog directories, e.g. -diff old/catalog catalog")
+		os.Exit(1)
+	}
+	if bundleOnly && bundlePath == "" {
+		fmt.Println("-bundle-only requires -bundle")
+		os.Exit(1)
+	}
+	if bundleOnly && serveSite {
+		fmt.Println("-bundle-only can't be used with -serve, there'd be nothing on disk to serve")
+		os.Exit(1)
+	}
+	var baseURL *url.URL
+	if baseURLFlag != "" {
+		var err error
+		if baseURL, err = parseSiteBaseURL(baseURLFlag); err != nil {
+			fmt.Println("Error:", err)
+			os.Exit(1)
+		}
+	}
 
 	fmt.Println("starting render...")
-	products, err := getCatalog()
-	if err != nil {
-		fmt.Println("Error getting catalog:", err)
+	summary := newRunSummary()
+	writeSummary := func() {
+		if summaryOut == "" {
+			return
+		}
+		if err := summary.write(summaryOut, summaryAppend); err != nil {
+			fmt.Println("Error writing run summary:", err)
+		}
+	}
+	// every exit from here on is recorded in the summary, succeed before returning and fail instead of os.Exit
+	succeed := func() {
+		summary.Success = true
+		writeSummary()
+	}
+	fail := func() {
+		summary.Success = false
+		writeSummary()
 		os.Exit(1)
 	}
 
-	if !skipUpdateCheck {
-		if err := products.checkForLabelUpdates(); err != nil {
-			fmt.Println("Error checking for FDA label updates:", err)
-			os.Exit(1)
+	// enums.json and config.json are read once, -watch rebuilds keep using them
+	settings, err := medcatalog.LoadSettings(catalogDir)
+	if err != nil {
+		fmt.Println("Error loading catalog settings:", err)
+		fail()
+	}
+	loadOpts.Settings = settings
+	loadOpts.Log = os.Stdout
+	fdaOpts.Log = os.Stdout
+
+	if refreshPharmClasses && !validateOnly {
+		if classes, err := medcatalog.RefreshPharmClassSnapshot(catalogDir); err != nil {
+			// the pinned snapshot (if any) is still usable offline
+			fmt.Println("Warning: failed refreshing pharm class snapshot, using the pinned one:", err)
+		} else {
+			fmt.Printf("pinned %d pharm classes to %s\n", len(classes), filepath.ToSlash(filepath.Join(catalogDir, medcatalog.PharmClassSnapshotFile)))
 		}
 	}
+	pharmClasses, err := medcatalog.LoadPharmClassSnapshot(catalogDir)
+	if err != nil {
+		fmt.Println("Error loading pharm class snapshot:", err)
+		fail()
+	}
+	if pharmClasses == nil {
+		fmt.Println("no pharm class snapshot found in " + catalogDir + ", run with -refresh-pharm-classes to create " + medcatalog.PharmClassSnapshotFile)
+	} else if err = medcatalog.ValidatePharmClassMapping(pharmClasses); err != nil {
+		fmt.Println("Error validating pharm class mapping:", err)
+		fail()
+	}
 
-	// validate the products
-	for _, p := range products {
-		if err = p.Validate(); err != nil {
-			fmt.Printf("Validation error for product %s: %v\n", p.BrandName, err)
-			os.Exit(1)
+	var placeholder *template.Template
+	if placeholderWhenEmpty {
+		// parse it up front so a broken placeholder is caught even when the catalog isn't empty
+		if placeholder, err = parsePlaceholderTemplate(); err != nil {
+			fmt.Println("Error loading placeholder:", err)
+			fail()
 		}
+	}
 
-		// TODO: check/generate css colors/classes from one source?
+	if diffCatalogsFlag {
+		d, err := diffCatalogDirs(flag.Arg(0), flag.Arg(1))
+		if err != nil {
+			fmt.Println("Error diffing catalogs:", err)
+			fail()
+		}
+		printCatalogDiff(d)
+		succeed()
+		return
 	}
 
-	// sort the products by ListPosition
-	// products with ListPosition 0 (not set) go to the end
-	sortedProducts := []product{}
-	unsortedProducts := []product{}
-	for _, p := range products {
-		if p.ListPosition > 0 {
-			sortedProducts = append(sortedProducts, p)
-		} else {
-			unsortedProducts = append(unsortedProducts, p)
+	if newProductBrand != "" {
+		path, err := medcatalog.ScaffoldProduct(catalogDir, newProductBrand)
+		if err != nil {
+			fmt.Println("Error creating new product:", err)
+			fail()
 		}
+		fmt.Println("created " + path + ", fill in the TODO and empty values and run with -validate-only to check it")
+		succeed()
+		return
 	}
-	// sort the sortedProducts slice
-	slices.SortFunc(sortedProducts, func(a, b product) int {
-		return a.ListPosition - b.ListPosition
-	})
-	// append the unsorted products to the end
-	sortedProducts = append(sortedProducts, unsortedProducts...)
-	products = sortedProducts
 
-	if err = renderIndex(products); err != nil {
-		fmt.Println("Error rendering index:", err)
-		os.Exit(1)
+	if formatFiles || formatCheck {
+		changed, err := medcatalog.FormatFiles(catalogDir, !formatCheck)
+		for _, file := range changed {
+			if formatCheck {
+				fmt.Println("not formatted: " + file)
+			} else {
+				fmt.Println("formatted " + file)
+			}
+		}
+		if err != nil {
+			fmt.Println("Error formatting catalog:", err)
+			fail()
+		}
+		if formatCheck && len(changed) > 0 {
+			fmt.Printf("%d catalog file(s) need formatting, run with -format\n", len(changed))
+			fail()
+		}
+		succeed()
+		return
 	}
-}
 
-func validateFDALabelLink(p product) error {
-	// make sure the updated date is in YYYY-MM-DD format
-	updateTime, err := time.Parse("2006-01-02", p.FDALabelUpdated)
+	products, skippedFiles, err := medcatalog.LoadWithOptions(catalogDir, loadOpts)
 	if err != nil {
-		return fmt.Errorf("Failed: FDA label updated date '%s' for product '%s' is not in YYYY-MM-DD format: %w", p.FDALabelUpdated, p.BrandName, err)
-	}
-	// it's impossible to have updated the label in the future
-	if updateTime.After(time.Now()) {
-		return fmt.Errorf("Failed: FDA label updated date '%s' for product '%s' is in the future", p.FDALabelUpdated, p.BrandName)
+		fmt.Println("Error getting catalog:", err)
+		summary.ErrorsByRule["catalog_load"]++
+		fail()
 	}
-	// make sure the link is to FDA's label repository
-	if !strings.HasPrefix(p.FDALabelFile, "https://www.accessdata.fda.gov/drugsatfda_docs/label/") {
-		return fmt.Errorf("Failed: FDA label file link '%s' for product '%s' is not a valid FDA label repository URL", p.FDALabelFile, p.BrandName)
+	reportWarnings := []buildReportWarning{}
+	for _, f := range skippedFiles {
+		fmt.Printf("Warning: skipping %s: %v\n", f.File, f.Err)
+		summary.WarningsByRule["parse_error"]++
+		reportWarnings = append(reportWarnings, buildReportWarning{"", f.File, "parse_error", f.Err.Error()})
 	}
-	// make sure the link is valid (parses as a URL)
-	u, err := url.ParseRequestURI(p.FDALabelFile)
-	if err != nil {
-		return fmt.Errorf("Failed: FDA label file link '%s' for product '%s' is not a valid URL", p.FDALabelFile, p.BrandName)
+	// listed again at the end so they aren't lost in the output
+	printSkippedFiles := func() {
+		if len(skippedFiles) == 0 {
+			return
+		}
+		fmt.Printf("skipped %d catalog file(s) that couldn't be parsed:\n", len(skippedFiles))
+		for _, f := range skippedFiles {
+			fmt.Println("  " + f.File)
+		}
 	}
-	// it has to be a link to a PDF
-	if !strings.HasSuffix(strings.ToLower(u.Path), ".pdf") {
-		return fmt.Errorf("Failed: FDA label file link '%s' for product '%s' is not a link to a PDF file", p.FDALabelFile, p.BrandName)
+
+	summary.Products = len(products)
+
+	if fdaReport {
+		rows, err := fdaLabelReport(products, fdaOpts)
+		if err != nil {
+			fmt.Println("Error checking for FDA label updates:", err)
+			fail()
+		}
+		printFDALabelReport(rows)
+		succeed()
+		return
 	}
-	// TODO send a HEAD request to make sure the link is reachable?
-	return nil
-}
 
-type product struct {
-	IngredientName          string        `json:"ingredient_name"`
-	BrandName               string        `json:"brand_name"`
-	MedicineType            string        `json:"medicine_type"`
-	AdminRoute              string        `json:"administration_route"`
-	DoseFrequency           string        `json:"dose_frequency,omitempty"`
-	Savings                 []savingsInfo `json:"savings"`
-	SkipFDALabel            bool          `json:"skip_fda_label,omitempty"`
-	FDALabelFile            string        `json:"fda_label_file,omitempty"`
-	FDALabelUpdated         string        `json:"fda_label_file_updated,omitempty"` // YYYY-MM-DD
-	FDALabelNeedsUpdate     bool          `json:"fda_label_needs_update,omitempty"`
-	FDALabelRecencyNotFound bool          `json:"fda_label_not_found,omitempty"` // if we couldn't find a matching label in the FDA lookup
-	ColorClass              string        `json:"color_class,omitempty"`
-	ListPosition            int           `json:"list_position,omitempty"`
-}
+	var fdaStats *medcatalog.FDALookupStats
+	// the products as the FDA check left them for updates.xml, nil when the check didn't run
+	var feedProducts []medcatalog.Product
+	if !skipUpdateCheck && !validateOnly {
+		stats, err := medcatalog.EnrichFromFDA(products, fdaOpts)
+		if err == nil || errors.Is(err, medcatalog.ErrUnverifiedNDCs) {
+			fmt.Println(stats)
+		}
+		// NDCs missing from the labels are a catalog mistake, not a lookup failure
+		if err != nil && fdaAdvisory && !errors.Is(err, medcatalog.ErrUnverifiedNDCs) {
+			// freshness is nice to have, publishing the otherwise valid site is not
+			fmt.Println("Warning: FDA label update check failed, rendering anyway:", err)
+			summary.WarningsByRule["fda_lookup"]++
+		} else if err != nil {
+			fmt.Println("Error checking for FDA label updates:", err)
+			summary.ErrorsByRule["fda_lookup"]++
+			fail()
+		} else {
+			fdaStats = &stats
+			summary.FDAChecks = stats.Lookups
+			summary.CacheHits = stats.CacheHits
+			feedProducts = slices.Clone(products)
+			for _, p := range products {
+				if p.FDALabelNeedsUpdate {
+					summary.LabelsNeedingUpdate++
+				}
+			}
+		}
+		if strict && summary.LabelsNeedingUpdate > 0 {
+			fmt.Printf("%d product(s) have a newer FDA label than the catalog records:\n", summary.LabelsNeedingUpdate)
+			for _, p := range products {
+				if !p.FDALabelNeedsUpdate {
+					continue
+				}
+				latest := p.FDALabelLatest
+				if latest == "" {
+					latest = "unknown"
+				}
+				recorded := p.FDALabelUpdated
+				if recorded == "" {
+					recorded = "none"
+				}
+				fmt.Printf("  %s (%s): label effective %s, recorded %s\n", p.BrandName, p.SourceFile(), latest, recorded)
+			}
+			fail()
+		}
+	}
 
-func (p product) Validate() error {
-	// Check that the unconstrained fields are not empty
-	if slices.Contains([]string{p.BrandName, p.IngredientName, p.DoseFrequency}, "") {
-		return fmt.Errorf("Failed: Brand name '%s', ingredient name '%s', "+
-			"and dose frequency '%s' cannot be empty for product '%s'",
-			p.BrandName, p.IngredientName, p.DoseFrequency, p.BrandName)
+	// validate the products, collecting the errors across the whole catalog
+	validationErrs := newErrorCollector(maxErrors)
+	for _, err := range medcatalog.Validate(products) {
+		validationErrs.Add(err)
+		var ve medcatalog.ValidationError
+		if errors.As(err, &ve) {
+			summary.ErrorsByRule[ve.Rule]++
+		}
+	}
+	for _, p := range products {
+		for _, w := range p.Warnings() {
+			if requireFDALabel && w.Rule == "missing_fda_label" {
+				validationErrs.Add(medcatalog.ValidationError{File: p.SourceFile(), Rule: w.Rule, Err: fmt.Errorf(
+					"Failed: product '%s' has %s", p.BrandName, w.Message)})
+				summary.ErrorsByRule[w.Rule]++
+				continue
+			}
+			fmt.Printf("Warning for product %s (%s): %s\n", p.BrandName, p.SourceFile(), w.Message)
+			summary.WarningsByRule[w.Rule]++
+			reportWarnings = append(reportWarnings, buildReportWarning{p.BrandName, p.SourceFile(), w.Rule, w.Message})
+		}
+	}
+	if checkLinksFlag {
+		fmt.Println("checking savings and FDA label links...")
+		broken, checked := checkLinks(context.Background(), products)
+		for _, b := range broken {
+			users := []string{}
+			for _, ref := range b.Products {
+				users = append(users, ref.String())
+				summary.WarningsByRule["broken_link"]++
+				reportWarnings = append(reportWarnings, buildReportWarning{ref.BrandName, ref.SourceFile, "broken_link", b.Error()})
+			}
+			fmt.Printf("Warning: %s, used by %s\n", b.Error(), strings.Join(users, ", "))
+		}
+		fmt.Printf("checked %d unique link(s), %d unreachable\n", checked, len(broken))
+		if len(broken) > 0 && linksFatal {
+			fmt.Printf("%d unreachable link(s) found\n", len(broken))
+			fail()
+		}
+	}
+	if validateOnly {
+		validationErrs.Print(os.Stdout)
+		printSkippedFiles()
+		fmt.Printf("validated %d products, %d errors, %d warnings\n", len(products), validationErrs.Count(), summary.warningCount())
+		failed := validationErrs.Count() > 0 || (failOnWarning && summary.warningCount() > 0)
+		if failed {
+			fail()
+		}
+		succeed()
+		return
+	}
+	if validationErrs.Count() > 0 {
+		validationErrs.Print(os.Stdout)
+		fmt.Printf("%d validation error(s) found\n", validationErrs.Count())
+		fail()
 	}
-	if len(p.Savings) == 0 {
-		return fmt.Errorf("Failed: Savings information is empty for product '%s'", p.BrandName)
+	// only a valid catalog is written to, so a failed run leaves the files as they were
+	if fdaWriteBack && feedProducts != nil {
+		updates, err := medcatalog.WriteBackLabelUpdates(products)
+		for _, u := range updates {
+			fmt.Printf("updated fda_label_file_updated in %s from %s to %s, check fda_label_file still points at the current label\n",
+				u.File, u.From, u.To)
+		}
+		if err != nil {
+			fmt.Println("Error writing FDA label dates back to the catalog:", err)
+			fail()
+		}
+		summary.LabelsNeedingUpdate = 0
+		for _, p := range products {
+			if p.FDALabelNeedsUpdate {
+				summary.LabelsNeedingUpdate++
+			}
+		}
 	}
 
-	// check the medicine type is one in the list
-	if err := medTypeEnum.CheckError(p.MedicineType); err != nil {
-		return errors.Join(fmt.Errorf("Failed: Medicine type '%s' for product '%s' is invalid. ", p.MedicineType, p.BrandName), err)
+	if auditSavingsReport {
+		printSavingsAudit(auditSavings(products))
 	}
 
-	// check the administration route is one in the list
-	if err := adminRouteEnum.CheckError(p.AdminRoute); err != nil {
-		return errors.Join(fmt.Errorf("Failed: Administration route '%s' for product '%s' is invalid. ", p.AdminRoute, p.BrandName), err)
+	products = medcatalog.Sort(products, sortBy)
+	medcatalog.Decorate(products)
+	if hideExpired {
+		medcatalog.HideExpiredSavings(products)
 	}
 
-	// validate each savings program's phone and link
-	for _, s := range p.Savings {
-		if err := s.Validate(); err != nil {
-			return fmt.Errorf("Failed: Savings program '%s' for product '%s' is invalid: %v", s.Description, p.BrandName, err)
+	if dumpPath != "" {
+		if err = dumpProducts(dumpPath, products); err != nil {
+			fmt.Println("Error dumping products:", err)
+			fail()
 		}
 	}
 
-	// if there is an fda label link, validate it
-	if strings.TrimSpace(p.FDALabelFile) != "" {
-		if err := validateFDALabelLink(p); err != nil {
-			return fmt.Errorf("Failed: FDA label validation for product '%s': %v", p.BrandName, err)
+	if renderDiff {
+		if err = previewRenderDiff(products); err != nil {
+			fmt.Println("Error previewing render diff:", err)
+			fail()
 		}
+		succeed()
+		return
 	}
 
-	return nil
-}
-
-type savingsInfo struct {
-	Type        string `json:"type"`
-	Description string `json:"description"`
-	Phone       string `json:"phone,omitempty"`
-	Link        string `json:"link,omitempty"`
-	Eligibility struct {
-		PrivateInsurance    bool     `json:"private_insurance,omitempty"`
-		GovernmentInsurance bool     `json:"government_insurance,omitempty"`
-		CashPay             bool     `json:"cash_pay,omitempty"`
-		OtherCriteria       []string `json:"other_criteria,omitempty"`
-	} `json:"eligibility,omitempty"`
-}
-
-func (s savingsInfo) Validate() error {
-	phoneRe := regexp.MustCompile(`^1-\d{3}-\d{3}-\d{4}$`)
-	if strings.TrimSpace(s.Description) == "" {
-		return errors.New("Savings description cannot be empty for product")
+	outputDir := outDir
+	if bundleOnly {
+		// stage the static files alongside the rendered ones in a throwaway directory
+		if outputDir, err = os.MkdirTemp("", "pugnarehealth-bundle-"); err != nil {
+			fmt.Println("Error creating bundle staging directory:", err)
+			fail()
+		}
+		defer os.RemoveAll(outputDir)
+		if err = copyDir(outDir, outputDir); err != nil {
+			fmt.Println("Error staging static files for bundle:", err)
+			fail()
+		}
+	}
+	// a fresh clone or a new -out directory might not have it yet
+	if err = os.MkdirAll(outputDir, 0o755); err != nil {
+		fmt.Printf("Error creating output directory %s: %v\n", outputDir, err)
+		fail()
+	}
+	if downloadLabelsFlag {
+		fmt.Println("downloading FDA label files...")
+		failed, err := downloadLabels(context.Background(), outputDir, products)
+		if err != nil {
+			fmt.Println("Error downloading FDA labels:", err)
+			fail()
+		}
+		for _, f := range failed {
+			fmt.Printf("Warning for product %s (%s): FDA label %s couldn't be downloaded, linking the FDA's copy: %v\n", f.BrandName, f.SourceFile, f.URL, f.Err)
+			summary.WarningsByRule["label_download"]++
+			reportWarnings = append(reportWarnings, buildReportWarning{f.BrandName, f.SourceFile, "label_download",
+				fmt.Sprintf("FDA label %s couldn't be downloaded: %v", f.URL, f.Err)})
+		}
 	}
-	if strings.TrimSpace(s.Phone) != "" {
-		if !phoneRe.MatchString(s.Phone) {
-			return fmt.Errorf("Phone number '%s' is not in the format 1-800-555-5555", s.Phone)
+	// everything generated besides the index, shared with -watch rebuilds
+	renderProductFiles := func(w *artifactWriter, products []medcatalog.Product) error {
+		if err := renderColorsCSS(w); err != nil {
+			return err
+		}
+		if err := renderProductPages(w, products); err != nil {
+			return err
+		}
+		if !noJSON {
+			if err := renderProductsJSON(w, products); err != nil {
+				return err
+			}
+		}
+		if baseURL != nil {
+			if err := renderSitemap(w, baseURL, products); err != nil {
+				return err
+			}
 		}
+		return nil
 	}
-	if strings.TrimSpace(s.Link) != "" {
-		if !strings.HasPrefix(s.Link, "http://") && !strings.HasPrefix(s.Link, "https://") {
-			return fmt.Errorf("Link '%s' for product '%s' is not a valid URL (must start with http:// or https://)", s.Link, s.Description)
+	artifacts := newArtifactWriter(outputDir)
+	if placeholder != nil && len(products) == 0 {
+		fmt.Println("catalog is empty, rendering the placeholder page")
+		if err = renderPlaceholder(artifacts, placeholder); err != nil {
+			fmt.Println("Error rendering placeholder:", err)
+			fail()
 		}
+	} else if err = renderIndex(artifacts, templates, products, watchMode && serveSite); err != nil {
+		fmt.Println("Error rendering index:", err)
+		fail()
 	}
-	if err := savingsTypeEnum.CheckError(s.Type); err != nil {
-		return fmt.Errorf("Invalid savings type '%s' for product '%s': %w", s.Type, s.Description, err)
+	if err = renderProductFiles(artifacts, products); err != nil {
+		fmt.Println("Error rendering:", err)
+		fail()
 	}
-
-	return nil
-}
-
-func getCatalog() (productList, error) {
-	// get the list of files in that folder, accumulate files that end in .json
-	files := []string{}
-	entries, err := os.ReadDir(repoPath + medCatalogPath)
-	if err != nil {
-		return []product{}, errors.Join(errors.New("failed reading catalog directory"), err)
+	if feedProducts != nil {
+		if err = renderUpdatesFeed(artifacts, baseURL, feedProducts); err != nil {
+			fmt.Println("Error rendering updates feed:", err)
+			fail()
+		}
+	}
+	if emitWidget {
+		if err = renderWidget(artifacts, products); err != nil {
+			fmt.Println("Error rendering widget:", err)
+			fail()
+		}
 	}
-	for _, entry := range entries {
-		if !entry.IsDir() && strings.HasSuffix(strings.ToLower(entry.Name()), ".json") {
-			files = append(files, entry.Name())
+	if handoutsFlag {
+		if err = renderHandouts(artifacts, products); err != nil {
+			fmt.Println("Error rendering handouts:", err)
+			fail()
 		}
 	}
-	fmt.Printf("Found %d JSON files in %s\n", len(files), medCatalogPath)
-	// for each file, read and parse the JSON into a product struct, accumulate into a slice
-	products := []product{}
-	for _, file := range files {
-		// fmt.Printf("Processing file: %s\n", file)
-		content, err := os.ReadFile(repoPath + medCatalogPath + file)
+	if a11yCheck {
+		indexPages := []string{}
+		for _, t := range templates {
+			indexPages = append(indexPages, t.Output)
+		}
+		findings, err := checkAccessibility(outputDir, indexPages, products)
 		if err != nil {
-			return []product{}, errors.Join(errors.New("failed reading file "+file), err)
+			fmt.Println("Error checking accessibility:", err)
+			fail()
 		}
-
-		var p product
-		if err = json.Unmarshal(content, &p); err != nil {
-			return []product{}, errors.Join(errors.New("failed parsing JSON in file "+file), err)
+		for _, f := range findings {
+			brandName := f.BrandName
+			if brandName == "" {
+				brandName = "(none)"
+			}
+			fmt.Printf("Accessibility warning for product %s (%s): %s\n", brandName, f.SourceFile, f.Message)
+			summary.WarningsByRule[f.Rule]++
+			reportWarnings = append(reportWarnings, buildReportWarning{f.BrandName, f.SourceFile, f.Rule, f.Message})
+		}
+		fmt.Printf("accessibility check found %d issue(s)\n", len(findings))
+	}
+	if writeReport {
+		if err = renderBuildReport(artifacts, products, reportWarnings, fdaStats); err != nil {
+			fmt.Println("Error writing build report:", err)
+			fail()
 		}
-		products = append(products, p)
 	}
+	artifacts.Report()
+	printSkippedFiles()
 
-	return products, nil
-}
+	if failOnWarning && summary.warningCount() > 0 {
+		fmt.Printf("%d warning(s) reported and -fail-on-warning is set\n", summary.warningCount())
+		fail()
+	}
+	if postRenderCmd != "" {
+		if err = runPostRenderCmd(postRenderCmd, outputDir); err != nil {
+			fmt.Println("Error running post-render command:", err)
+			fail()
+		}
+	}
 
-func renderIndex(products []product) error {
-	// open the index.gohtml file, read its content
-	content, err := os.ReadFile(repoPath + "index.gohtml")
-	if err != nil {
-		return errors.Join(errors.New("failed reading index.gohtml"), err)
+	if bundlePath != "" {
+		if err = writeBundle(outputDir, bundlePath); err != nil {
+			fmt.Println("Error bundling output:", err)
+			fail()
+		}
 	}
-	indexTemplate := string(content)
 
-	funcMap := template.FuncMap{
-		"hasPrefix": strings.HasPrefix,
-		"truncate": func(s string, n int) string {
-			if len(s) <= n {
-				return s
+	// recorded once the build is fully done, -watch and -serve keep running after it
+	succeed()
+
+	if !watchMode && !serveSite {
+		return
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	current := &atomic.Pointer[medcatalog.ProductList]{}
+	current.Store(&products)
+	var reloads *reloadBroker
+	if watchMode {
+		reloads = newReloadBroker()
+		// watch rebuilds skip the FDA lookups and only re-render the pages so each edit is quick, the
+		// initial build's FDA fields are carried over instead
+		enriched := products
+		rebuild := func() {
+			rebuilt, err := loadAndValidate(catalogDir, loadOpts, maxErrors, sortBy, hideExpired)
+			if err != nil {
+				fmt.Println("Rebuild failed, keeping the last good render:", err)
+				return
+			}
+			carryOverFDAFields(rebuilt, enriched)
+			w := newArtifactWriter(outputDir)
+			if err = renderIndex(w, templates, rebuilt, serveSite); err != nil {
+				fmt.Println("Error rendering index:", err)
+				return
+			}
+			if err = renderProductFiles(w, rebuilt); err != nil {
+				fmt.Println("Error rendering:", err)
+				return
 			}
-			return s[:n] + "..."
-		},
-		"subtract": func(a, b int) int {
-			return a - b
-		},
+			current.Store(&rebuilt)
+			reloads.Notify()
+		}
+		if !serveSite {
+			watchForChanges(ctx, catalogDir, templates, rebuild)
+			return
+		}
+		go watchForChanges(ctx, catalogDir, templates, rebuild)
 	}
+	if err = serve(ctx, serveAddr, outputDir, func() []medcatalog.Product { return *current.Load() }, reloads); err != nil {
+		// the build was already recorded as a success, this is only the server
+		fmt.Println("Error serving site:", err)
+		os.Exit(1)
+	}
+}
 
-	t, err := template.New("index").Funcs(funcMap).Parse(indexTemplate)
+// loadAndValidate reads, validates and sorts the catalog for a -watch rebuild, printing the problems
+// rather than exiting so the watcher keeps running
+func loadAndValidate(catalogDir string, loadOpts medcatalog.LoadOptions, maxErrors int, sortBy string, hideExpired bool) (medcatalog.ProductList, error) {
+	products, skippedFiles, err := medcatalog.LoadWithOptions(catalogDir, loadOpts)
 	if err != nil {
-		return errors.Join(errors.New("failed parsing index.gohtml template"), err)
+		return nil, err
 	}
-
-	data := struct {
-		Products []product
-	}{
-		Products: products,
+	for _, f := range skippedFiles {
+		fmt.Printf("Warning: skipping %s: %v\n", f.File, f.Err)
+	}
+	validationErrs := newErrorCollector(maxErrors)
+	for _, err := range medcatalog.Validate(products) {
+		validationErrs.Add(err)
+	}
+	if validationErrs.Count() > 0 {
+		validationErrs.Print(os.Stdout)
+		return nil, fmt.Errorf("%d validation error(s) found", validationErrs.Count())
 	}
+	products = medcatalog.Sort(products, sortBy)
+	medcatalog.Decorate(products)
+	if hideExpired {
+		medcatalog.HideExpiredSavings(products)
+	}
+	return products, nil
+}
 
-	outputFile, err := os.Create(repoPath + "public/index.html")
+// dumpProducts writes the in-memory products, with everything loading, lookups and flags have filled
+// in, as indented JSON
+func dumpProducts(path string, products []medcatalog.Product) error {
+	content, err := json.MarshalIndent(products, "", "    ")
 	if err != nil {
-		return errors.Join(errors.New("failed creating index.html"), err)
+		return errors.Join(errors.New("failed encoding products for dump"), err)
 	}
-	defer outputFile.Close()
-
-	if err = t.Execute(outputFile, data); err != nil {
-		return errors.Join(errors.New("failed executing template for index.html"), err)
+	if err = os.WriteFile(path, append(content, '\n'), 0o644); err != nil {
+		return errors.Join(errors.New("failed writing dump file "+path), err)
 	}
+	fmt.Printf("dumped %d products to %s\n", len(products), path)
+	return nil
+}
 
-	fmt.Println(outputFile.Name() + " rendered successfully.")
+func parseIndexTemplate() (*template.Template, error) {
+	return parseSiteTemplate(indexTemplate.File)
+}
 
+// parseSiteTemplate parses an index layout (index.gohtml or a -template one) with the shared funcs,
+// reusing the last parse while the file is unchanged
+func parseSiteTemplate(file string) (*template.Template, error) {
+	return loadTemplate(file, func() (*template.Template, error) {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, errors.Join(errors.New("failed reading "+file), err)
+		}
+		t, err := template.New(filepath.Base(file)).Funcs(templateFuncs()).Parse(string(content))
+		if err != nil {
+			return nil, errors.Join(errors.New("failed parsing "+file+" template"), err)
+		}
+		return t, nil
+	})
+}
+
+// indexData is what index.gohtml is executed with, LiveReload adds the -watch reload script
+type indexData struct {
+	Products   []medcatalog.Product
+	Groups     []productGroup
+	LiveReload bool
+	// BuildComment says which build rendered the page, at the top of index.html
+	BuildComment template.HTML
+}
+
+// productGroup is one medicine type's section of the index
+type productGroup struct {
+	Type     string
+	Products []medcatalog.Product
+}
+
+// groupByMedicineType splits the products into sections in the order their catalog lists the medicine
+// types, keeping their order within each section. types without any products get no section
+func groupByMedicineType(products []medcatalog.Product) []productGroup {
+	byType := map[string][]medcatalog.Product{}
+	for _, p := range products {
+		byType[string(p.MedicineType)] = append(byType[string(p.MedicineType)], p)
+	}
+	groups := []productGroup{}
+	if len(products) == 0 {
+		return groups
+	}
+	for _, medicineType := range products[0].Settings().MedicineTypes() {
+		if len(byType[medicineType]) > 0 {
+			groups = append(groups, productGroup{Type: medicineType, Products: byType[medicineType]})
+		}
+	}
+	return groups
+}
+
+// renderIndex renders each of the index layouts with the products
+func renderIndex(w *artifactWriter, templates []siteTemplate, products []medcatalog.Product, liveReload bool) error {
+	data := indexData{
+		Products:     products,
+		Groups:       groupByMedicineType(products),
+		LiveReload:   liveReload,
+		BuildComment: currentBuild().htmlComment(),
+	}
+	for _, st := range templates {
+		t, err := parseSiteTemplate(st.File)
+		if err != nil {
+			return err
+		}
+		err = w.WriteFunc(st.Output, func(buf *bytes.Buffer) error {
+			if err := t.Execute(buf, data); err != nil {
+				return errors.Join(errors.New("failed executing template for "+st.Output), err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// runMainEnv makes the test binary run main() instead of the tests, so runMain can test whole runs
+const runMainEnv = "PUGNAREHEALTH_RUN_MAIN"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(runMainEnv) == "1" {
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs the program with args (and any extra environment), returning its output and exit code
+func runMain(t *testing.T, env []string, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(append(os.Environ(), runMainEnv+"=1"), env...)
+	out, err := cmd.CombinedOutput()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return string(out), exitErr.ExitCode()
+	} else if err != nil {
+		t.Fatalf("failed running %v: %v", args, err)
+	}
+	return string(out), 0
+}
+
+// offlineEnv sends FDA requests to a closed port so the lookups fail without touching the network
+var offlineEnv = []string{"HTTPS_PROXY=http://127.0.0.1:1", "HTTP_PROXY=http://127.0.0.1:1"}
+
+func readSummary(t *testing.T, path string) runSummary {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("no run summary written: %v", err)
+	}
+	var s runSummary
+	if err = json.Unmarshal(content, &s); err != nil {
+		t.Fatalf("run summary isn't JSON: %v\n%s", err, content)
+	}
+	return s
+}
+
+func TestRunSummary(t *testing.T) {
+	brokenCatalog := t.TempDir()
+	if err := os.WriteFile(filepath.Join(brokenCatalog, "broken.json"), []byte("{"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name         string
+		env          []string
+		args         []string
+		wantExit     int
+		wantProducts int
+		wantErrors   map[string]int
+	}{
+		{"success", nil, []string{"-catalog", "testdata/catalog", "-skip-update-check"}, 0, 2, map[string]int{}},
+		{"catalog load error", nil, []string{"-catalog", brokenCatalog, "-skip-update-check"}, 1, 0,
+			map[string]int{"catalog_load": 1}},
+		{"FDA lookup error", offlineEnv, []string{"-catalog", "testdata/catalog", "-no-cache", "-fda-max-retries", "0"}, 1, 2,
+			map[string]int{"fda_lookup": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			summaryPath := filepath.Join(t.TempDir(), "summary.json")
+			args := append([]string{"-out", t.TempDir(), "-summary-out", summaryPath}, tt.args...)
+			out, code := runMain(t, tt.env, args...)
+			if code != tt.wantExit {
+				t.Fatalf("exit code = %d, want %d\n%s", code, tt.wantExit, out)
+			}
+			s := readSummary(t, summaryPath)
+			if s.Success != (tt.wantExit == 0) {
+				t.Errorf("summary success = %t, want %t", s.Success, tt.wantExit == 0)
+			}
+			if s.Products != tt.wantProducts {
+				t.Errorf("summary products = %d, want %d", s.Products, tt.wantProducts)
+			}
+			if len(s.ErrorsByRule) != len(tt.wantErrors) {
+				t.Errorf("summary errors_by_rule = %v, want %v", s.ErrorsByRule, tt.wantErrors)
+			}
+			for rule, n := range tt.wantErrors {
+				if s.ErrorsByRule[rule] != n {
+					t.Errorf("summary errors_by_rule[%s] = %d, want %d", rule, s.ErrorsByRule[rule], n)
+				}
+			}
+		})
+	}
+}
+
+// loadTestCatalog loads and decorates the fixture products in testdata/catalog
+func loadTestCatalog(t *testing.T) []medcatalog.Product {
+	t.Helper()
+	products, err := medcatalog.Load("testdata/catalog")
+	if err != nil {
+		t.Fatalf("failed loading the test catalog: %v", err)
+	}
+	products = medcatalog.Sort(products, medcatalog.DefaultSortKey)
+	medcatalog.Decorate(products)
+	return products
+}
+
+func TestFDAAdvisoryStillRenders(t *testing.T) {
+	outDir := t.TempDir()
+	out, code := runMain(t, offlineEnv, "-catalog", "testdata/catalog", "-out", outDir,
+		"-fda-advisory", "-no-cache", "-fda-max-retries", "0")
+	if code != 0 {
+		t.Fatalf("exit code with a failed FDA lookup under -fda-advisory = %d, want 0\n%s", code, out)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "index.html")); err != nil {
+		t.Errorf("index.html wasn't rendered: %v", err)
+	}
+}
+
+func TestAffordableBadge(t *testing.T) {
+	tmpl, err := parseIndexTemplate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range loadTestCatalog(t) {
+		var buf bytes.Buffer
+		if err = tmpl.ExecuteTemplate(&buf, "drug-card", p); err != nil {
+			t.Fatalf("failed rendering card for %s: %v", p.BrandName, err)
+		}
+		// only Sugarbane's program is open to cash-pay patients
+		want := p.BrandName == "Sugarbane"
+		if got := strings.Contains(buf.String(), "Low-cost option"); got != want {
+			t.Errorf("%s card shows the low-cost badge = %t, want %t", p.BrandName, got, want)
+		}
+	}
+}
+
+// writeFDACacheFixture caches an exact brand name search result for the brand in dir, so a run with
+// -fda-cache-dir dir gets the label without the network
+func writeFDACacheFixture(t *testing.T, dir, brandName, effectiveTime string) {
+	t.Helper()
+	q := url.Values{"search": {`openfda.brand_name.exact:"` + brandName + `"`}, "limit": {"30"}}
+	entry := map[string]any{
+		"brand_name": brandName + " exact",
+		"url":        "https://api.fda.gov/drug/label.json?" + q.Encode(),
+		"fetched_at": time.Now(),
+		"data": map[string]any{
+			"meta":    map[string]any{"results": map[string]int{"total": 1}},
+			"results": []any{map[string]any{"effective_time": effectiveTime, "openfda": map[string][]string{"brand_name": {brandName}}}},
+		},
+	}
+	content, err := json.Marshal(entry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, medcatalog.Slugify(brandName+" exact")+".json"), content, 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFDAWriteBackOnlyAfterValidation(t *testing.T) {
+	product, err := os.ReadFile("testdata/catalog/glucozen.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	invalid := bytes.Replace(product, []byte(`"brand_name": "Glucozen",`), []byte(`"brand_name": "Glucozen",
+  "ndcs": ["12345"],`), 1)
+	tests := []struct {
+		name     string
+		content  []byte
+		wantCode int
+		wantDate string
+	}{
+		{"valid catalog", product, 0, "2025-06-01"},
+		{"invalid catalog", invalid, 1, "2025-03-01"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			catalogDir, cacheDir := t.TempDir(), t.TempDir()
+			path := filepath.Join(catalogDir, "glucozen.json")
+			if err := os.WriteFile(path, tt.content, 0o644); err != nil {
+				t.Fatal(err)
+			}
+			writeFDACacheFixture(t, cacheDir, "Glucozen", "20250601")
+			out, code := runMain(t, offlineEnv, "-catalog", catalogDir, "-out", t.TempDir(), "-fda-cache-dir", cacheDir,
+				"-fda-max-retries", "0", "-fda-write-back")
+			if code != tt.wantCode {
+				t.Fatalf("exit code = %d, want %d\n%s", code, tt.wantCode, out)
+			}
+			written, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Contains(written, []byte(`"fda_label_file_updated": "`+tt.wantDate+`"`)) {
+				t.Errorf("catalog file after the run has %s, want fda_label_file_updated %s\n%s", written, tt.wantDate, out)
+			}
+		})
+	}
+}
+
+func TestFDAAdvisoryKeepsCatalogFailures(t *testing.T) {
+	out, code := runMain(t, offlineEnv, "-catalog", "testdata/catalog", "-out", t.TempDir(), "-fda-advisory", "-strict")
+	if code != 1 || !strings.Contains(out, "-fda-advisory") {
+		t.Errorf("-fda-advisory with -strict = exit %d, want 1 rejecting the combination\n%s", code, out)
+	}
+
+	product, err := os.ReadFile("testdata/catalog/glucozen.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	catalogDir, cacheDir := t.TempDir(), t.TempDir()
+	withNDC := bytes.Replace(product, []byte(`"brand_name": "Glucozen",`), []byte(`"brand_name": "Glucozen",
+  "ndcs": ["0169-4130-13"],`), 1)
+	if err = os.WriteFile(filepath.Join(catalogDir, "glucozen.json"), withNDC, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the label doesn't list the product's NDC
+	writeFDACacheFixture(t, cacheDir, "Glucozen", "20250301")
+	out, code = runMain(t, offlineEnv, "-catalog", catalogDir, "-out", t.TempDir(), "-fda-cache-dir", cacheDir,
+		"-fda-max-retries", "0", "-fda-advisory", "-verify-ndc")
+	if code != 1 || !strings.Contains(out, "0169-4130-13") {
+		t.Errorf("-fda-advisory with an NDC missing from the label = exit %d, want 1 naming the NDC\n%s", code, out)
+	}
+}
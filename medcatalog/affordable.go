@@ -0,0 +1,67 @@
+package medcatalog
+
+import (
+	"cmp"
+	"math"
+	"slices"
+	"time"
+)
+
+// affordableCopayCap is the most a copay card can cap monthly costs at (in dollars) for the
+// product to get the low-cost badge by default, a catalog's config.json can change it
+var affordableCopayCap = 35.0
+
+// isAffordable is the low-cost badge heuristic: a product is affordable when any of its savings
+// programs is open to cash-pay patients, or it has a copay card whose stated copay_cap is at or
+// under the settings' affordable copay cap. expired programs don't count
+func (p Product) isAffordable() bool {
+	copayCap := p.Settings().affordableCopayCap
+	for _, s := range p.Savings {
+		if s.Expired {
+			continue
+		}
+		if s.Eligibility.CashPay {
+			return true
+		}
+		if s.Type == "Copay Discount Card" && s.CopayCap > 0 && s.CopayCap <= copayCap {
+			return true
+		}
+	}
+	return false
+}
+
+// Decorate fills in the derived view fields used when rendering. the color class always
+// comes from the medicine type, a different one set in the catalog is warned about (see Warnings).
+// savings programs are put in priority order, ones without a priority keep their catalog order after
+// the rest
+func Decorate(products []Product) {
+	now := time.Now()
+	for i := range products {
+		products[i].Savings = slices.Clone(products[i].Savings)
+		slices.SortStableFunc(products[i].Savings, func(a, b SavingsInfo) int {
+			return cmp.Compare(a.sortPriority(), b.sortPriority())
+		})
+		for j := range products[i].Savings {
+			products[i].Savings[j].Expired = products[i].Savings[j].expired(now)
+		}
+		products[i].Affordable = products[i].isAffordable()
+		products[i].ColorClass = products[i].derivedColorClass()
+	}
+}
+
+// HideExpiredSavings drops the savings programs Decorate marked as expired
+func HideExpiredSavings(products []Product) {
+	for i := range products {
+		products[i].Savings = slices.DeleteFunc(slices.Clone(products[i].Savings), func(s SavingsInfo) bool {
+			return s.Expired
+		})
+	}
+}
+
+// sortPriority is the program's priority, unset (0) sorts last
+func (s SavingsInfo) sortPriority() int {
+	if s.Priority == 0 {
+		return math.MaxInt
+	}
+	return s.Priority
+}
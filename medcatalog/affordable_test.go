@@ -0,0 +1,39 @@
+package medcatalog
+
+import "testing"
+
+func TestIsAffordable(t *testing.T) {
+	copayCard := func(cap float64) SavingsInfo {
+		s := testSavings("Copay card")
+		s.CopayCap = cap
+		return s
+	}
+	cashPay := SavingsInfo{Type: "Patient Assistance Program", Description: "Free if eligible"}
+	cashPay.Eligibility.CashPay = true
+	expiredCashPay := cashPay
+	expiredCashPay.Expired = true
+	cappedAssistance := cashPay
+	cappedAssistance.Eligibility.CashPay = false
+	cappedAssistance.CopayCap = 10
+
+	tests := []struct {
+		name    string
+		savings []SavingsInfo
+		want    bool
+	}{
+		{"open to cash pay", []SavingsInfo{cashPay}, true},
+		{"copay card at the cap", []SavingsInfo{copayCard(affordableCopayCap)}, true},
+		{"copay card over the cap", []SavingsInfo{copayCard(affordableCopayCap + 1)}, false},
+		{"copay card without a cap", []SavingsInfo{copayCard(0)}, false},
+		{"cap on a program that isn't a copay card", []SavingsInfo{cappedAssistance}, false},
+		{"expired cash pay program", []SavingsInfo{expiredCashPay}, false},
+		{"no savings", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := (Product{Savings: tt.savings}).isAffordable(); got != tt.want {
+				t.Errorf("isAffordable() = %t, want %t", got, tt.want)
+			}
+		})
+	}
+}
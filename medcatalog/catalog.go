@@ -0,0 +1,322 @@
+// Package medcatalog loads, validates and enriches the medication savings catalog: Load reads the
+// catalog files, Validate checks them and EnrichFromFDA fills in what the FDA's label API knows. it
+// returns errors rather than exiting so it can be used outside the site generator.
+package medcatalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// DefaultDir is the catalog directory of the site, relative to the root of the repo
+const DefaultDir = "catalog/"
+
+// folders with this name anywhere in the catalog hold products that are no longer listed
+const retiredCatalogDir = "catalog-retired"
+
+// MedTypeEnum is the built-in medicine types, a catalog's enums.json can replace them (see
+// Settings). this and the other built-in sets below are never changed after startup
+var MedTypeEnum = NewEnum([]string{
+	"CGM",
+	"SGLT-2",
+	"GLP-1",
+	"DPP-4",
+	"Insulin Delivery System",
+	"Insulin",
+})
+
+var adminRouteEnum = NewEnum([]string{
+	"Oral Tablet",
+	"Subcutaneous Injection",
+	"Automatic Applicator",
+	"Tubeless Insulin Pump",
+})
+
+// deviceMedicineTypes are the medicine types of devices (CGMs, pumps, etc.) rather than drugs,
+// devices don't have FDA drug labels so they are exempt from the label checks and lookups
+var deviceMedicineTypes = NewEnum([]string{
+	"CGM",
+	"Insulin Delivery System",
+})
+
+// deviceRoutes are the administration routes only devices use, every one is also in adminRouteEnum
+var deviceRoutes = NewEnum([]string{
+	"Automatic Applicator",
+	"Tubeless Insulin Pump",
+})
+
+var savingsTypeEnum = NewEnum([]string{
+	"Copay Discount Card",
+	"Patient Assistance Program",
+	"Medicare Prescription Payment Plan",
+	"Free Trial Offer",
+})
+
+// doseFrequencyEnum is the controlled vocabulary for dose_frequency so the same schedule reads the
+// same way on every card, N/A is for products like CGMs without a dosing schedule
+var doseFrequencyEnum = NewEnum([]string{
+	"Once Daily",
+	"Twice Daily",
+	"Once Weekly",
+	"Bolus Dosing",
+	"Every 3 days (pod change)",
+	"Once every 10 days",
+	"Once every 15 days",
+	"N/A",
+})
+
+// catalog fields checked against the catalog's enums when they're loaded, see enumDecodeError
+type (
+	medicineType  string
+	adminRoute    string
+	doseFrequency string
+	savingsType   string
+)
+
+func (v medicineType) String() string  { return string(v) }
+func (v adminRoute) String() string    { return string(v) }
+func (v doseFrequency) String() string { return string(v) }
+func (v savingsType) String() string   { return string(v) }
+
+// enumDecodeError fails a product with a value that isn't in the settings' enums, so a typo in a
+// catalog file fails while loading that file. empty values (as -new-product leaves them) are let
+// through for Validate to report along with the values to pick from
+func (s *Settings) enumDecodeError(p Product) error {
+	type check struct {
+		value  string
+		values enum
+	}
+	checks := []check{
+		{string(p.MedicineType), s.medicineTypes},
+		{string(p.AdminRoute), s.adminRoutes},
+		{string(p.DoseFrequency), s.doseFrequencies},
+	}
+	for _, sv := range p.Savings {
+		checks = append(checks, check{string(sv.Type), s.savingsTypes})
+	}
+	for _, c := range checks {
+		if err := c.values.CheckError(c.value); err != nil && c.value != "" {
+			return err
+		}
+	}
+	return nil
+}
+
+var nonSlugCharsRe = regexp.MustCompile(`[^a-z0-9]+`)
+
+// Slugify turns a brand name like "Omnipod 5" into "omnipod-5"
+func Slugify(s string) string {
+	return strings.Trim(nonSlugCharsRe.ReplaceAllString(strings.ToLower(s), "-"), "-")
+}
+
+// assignSlugs fills in slugs for products that don't set one. a derived slug that collides with
+// another product gets a counter appended (wegovy, wegovy-2, ...) in catalog order so the pages
+// stay put between runs. slugs set explicitly in the catalog are left alone and checked for
+// duplicates during validation.
+func assignSlugs(products []Product) {
+	used := map[string]bool{}
+	for _, p := range products {
+		if p.Slug != "" {
+			used[p.Slug] = true
+		}
+	}
+	for i := range products {
+		if products[i].Slug != "" {
+			continue
+		}
+		base := Slugify(products[i].BrandName)
+		slug := base
+		for n := 2; used[slug]; n++ {
+			slug = fmt.Sprintf("%s-%d", base, n)
+		}
+		used[slug] = true
+		products[i].Slug = slug
+	}
+}
+
+// logf writes a progress message to w, if there is one to write to
+func logf(w io.Writer, format string, args ...any) {
+	if w != nil {
+		fmt.Fprintf(w, format, args...)
+	}
+}
+
+// catalogPath is a file in the catalog directory, with forward slashes so messages read the same everywhere
+func catalogPath(dir, file string) string {
+	return filepath.ToSlash(filepath.Join(dir, file))
+}
+
+// catalogSettingsFiles sit at the top of the catalog directory alongside the products
+var catalogSettingsFiles = []string{enumsFile, catalogSchemaFile, ConfigFile, PharmClassSnapshotFile}
+
+// catalogFiles walks the catalog folder and any subfolders (besides the retired one), returning the
+// JSON and YAML product files relative to the catalog in a deterministic order
+func catalogFiles(dir string) ([]string, error) {
+	files := []string{}
+	if _, err := os.Stat(dir); errors.Is(err, os.ErrNotExist) {
+		// an empty catalog is fine, a missing one means the path is misconfigured
+		return nil, fmt.Errorf("catalog directory %s does not exist", dir)
+	}
+	root := filepath.Clean(dir)
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == retiredCatalogDir {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() && slices.Contains(catalogSettingsFiles, d.Name()) && filepath.Dir(path) == root {
+			return nil // settings, not products
+		}
+		if !d.IsDir() && isCatalogFile(d.Name()) {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Join(errors.New("failed reading catalog directory"), err)
+	}
+	// WalkDir is lexical per directory, sort the full relative paths so the order is deterministic overall
+	slices.Sort(files)
+	return files, nil
+}
+
+// LoadOptions changes how LoadWithOptions treats catalog files it can't use
+type LoadOptions struct {
+	// ContinueOnParseError skips files that can't be read or parsed instead of failing the whole
+	// load, they're returned as SkippedFiles. schema violations still fail it
+	ContinueOnParseError bool
+	// Settings are the enums and config the products are loaded with, LoadSettings(dir) when nil
+	Settings *Settings
+	// Log gets progress messages, they're dropped when it's nil
+	Log io.Writer
+}
+
+// SkippedFile is a catalog file left out of the load because it couldn't be parsed
+type SkippedFile struct {
+	File string
+	Err  error
+}
+
+// Load reads every product file in the catalog directory dir, checking each against the catalog
+// schema and decoding it strictly against the directory's settings (see LoadSettings), which the
+// products carry. the products aren't validated, see Validate
+func Load(dir string) (ProductList, error) {
+	products, _, err := LoadWithOptions(dir, LoadOptions{})
+	return products, err
+}
+
+// LoadWithOptions is Load, also returning the files opts had it skip
+func LoadWithOptions(dir string, opts LoadOptions) (ProductList, []SkippedFile, error) {
+	files, err := catalogFiles(dir)
+	if err != nil {
+		return []Product{}, nil, err
+	}
+	logf(opts.Log, "Found %d catalog files in %s\n", len(files), dir)
+	settings := opts.Settings
+	if settings == nil {
+		if settings, err = LoadSettings(dir); err != nil {
+			return []Product{}, nil, err
+		}
+	}
+	schema, err := loadCatalogSchema(dir, settings)
+	if err != nil {
+		return []Product{}, nil, err
+	}
+
+	// read every file up front, a file that can't be parsed either fails the load or is set aside
+	skipped := []SkippedFile{}
+	contents := map[string][]byte{}
+	usable := []string{}
+	for _, file := range files {
+		content, err := readCatalogFile(dir, file)
+		if err == nil && !json.Valid(content) {
+			var v any
+			err = errors.Join(errors.New("failed parsing JSON in file "+file), json.Unmarshal(content, &v))
+		}
+		if err != nil && opts.ContinueOnParseError {
+			skipped = append(skipped, SkippedFile{File: catalogPath(dir, file), Err: err})
+			continue
+		} else if err != nil {
+			return []Product{}, nil, err
+		}
+		contents[file] = content
+		usable = append(usable, file)
+	}
+
+	// check every file against the schema first so all the violations are reported at once, with
+	// paths editors can follow
+	schemaErrs := []error{}
+	for _, file := range usable {
+		if schema == nil {
+			break
+		}
+		violations, err := schemaViolations(schema, contents[file])
+		if err != nil {
+			return []Product{}, nil, errors.Join(errors.New("failed parsing JSON in file "+file), err)
+		}
+		for _, v := range violations {
+			schemaErrs = append(schemaErrs, fmt.Errorf("%s: %s", catalogPath(dir, file), v))
+		}
+	}
+	if len(schemaErrs) > 0 {
+		return []Product{}, nil, errors.Join(append([]error{fmt.Errorf("%d catalog schema violation(s)", len(schemaErrs))}, schemaErrs...)...)
+	}
+
+	// parse the JSON of each file into a product struct, accumulate into a slice
+	products := []Product{}
+	for _, file := range usable {
+		p, err := decodeProduct(file, contents[file], settings)
+		if err != nil && opts.ContinueOnParseError {
+			skipped = append(skipped, SkippedFile{File: catalogPath(dir, file), Err: err})
+			continue
+		} else if err != nil {
+			return []Product{}, nil, err
+		}
+		p.normalizeNames()
+		// store phone numbers in one format so they render consistently, ones that don't normalize
+		// are left as is for validation to report
+		for i := range p.Savings {
+			if phone, ok := normalizePhone(p.Savings[i].Phone, p.Savings[i].Country); ok {
+				p.Savings[i].Phone = phone
+			}
+		}
+		p.sourceFile = catalogPath(dir, file)
+		p.settings = settings
+		products = append(products, p)
+	}
+	assignSlugs(products)
+
+	return products, skipped, nil
+}
+
+// decodeProduct parses a catalog file's JSON into a product. unknown keys are almost always typos
+// (brandName for brand_name), fail on them rather than render a product with an empty field
+func decodeProduct(file string, content []byte, settings *Settings) (Product, error) {
+	var p Product
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return Product{}, errors.Join(errors.New("failed parsing JSON in file "+file), err)
+	}
+	if dec.More() {
+		return Product{}, errors.New("failed parsing JSON in file " + file + ": unexpected data after the product object")
+	}
+	if err := settings.enumDecodeError(p); err != nil {
+		return Product{}, errors.Join(errors.New("failed parsing JSON in file "+file), err)
+	}
+	return p, nil
+}
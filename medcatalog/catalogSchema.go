@@ -0,0 +1,100 @@
+package medcatalog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+)
+
+var schemaMessages = message.NewPrinter(language.English)
+
+// optional, in the catalog directory, JSON Schema every catalog file is checked against before decoding
+const catalogSchemaFile = "schema.json"
+
+// loadCatalogSchema compiles catalog/schema.json, returning nil if there isn't one. the enums in the
+// file are the built-in defaults, they're swapped for the catalog's settings so enums.json and
+// config.json stay the source of truth. empty values are let through too, Validate reports those
+// with the choices
+func loadCatalogSchema(dir string, settings *Settings) (*jsonschema.Schema, error) {
+	path := catalogPath(dir, catalogSchemaFile)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Join(errors.New("failed reading "+path), err)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(content))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed parsing JSON in "+path), err)
+	}
+	if defs, ok := doc.(map[string]any)["$defs"].(map[string]any); ok {
+		for name, values := range map[string]enum{
+			"medicineType":  settings.medicineTypes,
+			"adminRoute":    settings.adminRoutes,
+			"savingsType":   settings.savingsTypes,
+			"doseFrequency": settings.doseFrequencies,
+		} {
+			if def, ok := defs[name].(map[string]any); ok {
+				def["enum"] = toAnySlice(append([]string{""}, values...))
+			}
+		}
+	}
+
+	c := jsonschema.NewCompiler()
+	if err = c.AddResource(path, doc); err != nil {
+		return nil, errors.Join(errors.New("failed loading schema "+path), err)
+	}
+	schema, err := c.Compile(path)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed compiling schema "+path), err)
+	}
+	return schema, nil
+}
+
+func toAnySlice(values []string) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+// schemaViolations checks a catalog file's content against the schema, returning one message per
+// violation prefixed with the JSON Pointer to the offending value
+func schemaViolations(schema *jsonschema.Schema, content []byte) ([]string, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(content))
+	if err != nil {
+		return nil, err
+	}
+	err = schema.Validate(doc)
+	var ve *jsonschema.ValidationError
+	if err == nil {
+		return nil, nil
+	} else if !errors.As(err, &ve) {
+		return nil, err
+	}
+	violations := []string{}
+	collectSchemaViolations(ve, &violations)
+	slices.Sort(violations)
+	return slices.Compact(violations), nil
+}
+
+// collectSchemaViolations walks down to the errors that actually failed, the ones above them only
+// say a group of keywords (a $ref, properties) didn't validate
+func collectSchemaViolations(ve *jsonschema.ValidationError, violations *[]string) {
+	if len(ve.Causes) > 0 {
+		for _, cause := range ve.Causes {
+			collectSchemaViolations(cause, violations)
+		}
+		return
+	}
+	location := "/" + strings.Join(ve.InstanceLocation, "/")
+	*violations = append(*violations, fmt.Sprintf("%s: %s", location, ve.ErrorKind.LocalizedString(schemaMessages)))
+}
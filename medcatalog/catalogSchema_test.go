@@ -0,0 +1,72 @@
+package medcatalog
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// jsonFieldNames lists the JSON names of a struct's exported fields
+func jsonFieldNames(t reflect.Type) []string {
+	names := []string{}
+	for _, field := range reflect.VisibleFields(t) {
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		} else if name == "" {
+			name = field.Name
+		}
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// schemaPropertyNames lists the keys of a schema object's properties
+func schemaPropertyNames(t *testing.T, schema map[string]any) []string {
+	t.Helper()
+	properties, ok := schema["properties"].(map[string]any)
+	if !ok {
+		t.Fatalf("schema object has no properties: %v", schema)
+	}
+	names := []string{}
+	for name := range properties {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+func TestCatalogSchemaMatchesProduct(t *testing.T) {
+	content, err := os.ReadFile("../" + DefaultDir + catalogSchemaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schema map[string]any
+	if err = json.Unmarshal(content, &schema); err != nil {
+		t.Fatalf("failed parsing %s: %v", catalogSchemaFile, err)
+	}
+	savings := schema["$defs"].(map[string]any)["savings"].(map[string]any)
+	eligibility := savings["properties"].(map[string]any)["eligibility"].(map[string]any)
+	eligibilityField, _ := reflect.TypeFor[SavingsInfo]().FieldByName("Eligibility")
+
+	for _, tt := range []struct {
+		name   string
+		fields []string
+		schema map[string]any
+	}{
+		{"product", jsonFieldNames(reflect.TypeFor[Product]()), schema},
+		{"savings", jsonFieldNames(reflect.TypeFor[SavingsInfo]()), savings},
+		{"eligibility", jsonFieldNames(eligibilityField.Type), eligibility},
+	} {
+		if properties := schemaPropertyNames(t, tt.schema); !slices.Equal(properties, tt.fields) {
+			t.Errorf("%s properties in %s are %v, the struct's JSON fields are %v", tt.name, catalogSchemaFile, properties, tt.fields)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package medcatalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// catalog files can be JSON or YAML, YAML is converted to JSON on load so both go through the same
+// schema check and strict decoding
+var catalogExtensions = []string{".json", ".yaml", ".yml"}
+
+func isCatalogFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range catalogExtensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// readCatalogFile reads a catalog file (relative to the catalog), returning its content as JSON
+func readCatalogFile(dir, file string) ([]byte, error) {
+	content, err := os.ReadFile(catalogPath(dir, file))
+	if err != nil {
+		return nil, errors.Join(errors.New("failed reading file "+file), err)
+	}
+	if strings.ToLower(filepath.Ext(file)) == ".json" {
+		return content, nil
+	}
+	var node yaml.Node
+	if err = yaml.Unmarshal(content, &node); err != nil {
+		return nil, errors.Join(errors.New("failed parsing YAML in file "+file), err)
+	}
+	doc, err := yamlNodeValue(&node)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed parsing YAML in file "+file), err)
+	}
+	if content, err = json.Marshal(doc); err != nil {
+		return nil, errors.Join(errors.New("failed converting YAML in file "+file+" to JSON"), err)
+	}
+	return content, nil
+}
+
+// yamlNodeValue converts a YAML node to the values encoding/json works with. dates are kept as the
+// text they were written as (fda_label_file_updated: 2026-03-04) rather than becoming timestamps
+func yamlNodeValue(node *yaml.Node) (any, error) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		if len(node.Content) == 0 {
+			return nil, nil
+		}
+		return yamlNodeValue(node.Content[0])
+	case yaml.AliasNode:
+		return yamlNodeValue(node.Alias)
+	case yaml.MappingNode:
+		m := make(map[string]any, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			var key string
+			if err := node.Content[i].Decode(&key); err != nil {
+				return nil, fmt.Errorf("line %d: keys have to be strings: %w", node.Content[i].Line, err)
+			}
+			v, err := yamlNodeValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			m[key] = v
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		s := make([]any, 0, len(node.Content))
+		for _, item := range node.Content {
+			v, err := yamlNodeValue(item)
+			if err != nil {
+				return nil, err
+			}
+			s = append(s, v)
+		}
+		return s, nil
+	}
+	if node.ShortTag() == "!!timestamp" {
+		return node.Value, nil
+	}
+	var v any
+	if err := node.Decode(&v); err != nil {
+		return nil, fmt.Errorf("line %d: %w", node.Line, err)
+	}
+	return v, nil
+}
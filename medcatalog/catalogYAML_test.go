@@ -0,0 +1,69 @@
+package medcatalog
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const testYAMLProduct = `ingredient_name: Glucozide
+brand_name: Glucozen
+medicine_type: GLP-1
+administration_route: Subcutaneous Injection
+dose_frequency: Once Weekly
+savings:
+  - type: Patient Assistance Program
+    description: Free for eligible patients
+    phone: (800) 555-0100
+    eligibility:
+      government_insurance: true
+      cash_pay: true
+      income_limit_fpl_percent: 400
+      other_criteria:
+        - US resident
+fda_label_file: https://www.accessdata.fda.gov/drugsatfda_docs/label/2025/000001s001lbl.pdf
+fda_label_file_updated: 2025-03-01
+`
+
+func TestLoadYAMLProduct(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "glucozen.yaml"), []byte(testYAMLProduct), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	products, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() with a YAML product failed: %v", err)
+	}
+	if len(products) != 1 {
+		t.Fatalf("Load() returned %d products, want 1", len(products))
+	}
+	p := products[0]
+	if p.FDALabelUpdated != "2025-03-01" {
+		t.Errorf("fda_label_file_updated = %q, want the date as written", p.FDALabelUpdated)
+	}
+	if len(p.Savings) != 1 {
+		t.Fatalf("got %d savings programs, want 1", len(p.Savings))
+	}
+	e := p.Savings[0].Eligibility
+	if !e.GovernmentInsurance || !e.CashPay || e.PrivateInsurance || e.IncomeLimitFPLPercent != 400 ||
+		!slices.Equal(e.OtherCriteria, []string{"US resident"}) {
+		t.Errorf("eligibility = %+v, want government insurance and cash pay under 400%% FPL for US residents", e)
+	}
+	if p.Savings[0].Phone != "1-800-555-0100" {
+		t.Errorf("phone = %q, want it normalized like a JSON product's", p.Savings[0].Phone)
+	}
+	if errs := Validate(products); len(errs) > 0 {
+		t.Errorf("YAML product doesn't validate: %v", errs)
+	}
+
+	// a misspelled key inside eligibility fails like it does in JSON
+	typo := strings.Replace(testYAMLProduct, "cash_pay:", "cashpay:", 1)
+	if err = os.WriteFile(filepath.Join(dir, "glucozen.yaml"), []byte(typo), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Load(dir); err == nil {
+		t.Error("Load() with an unknown eligibility key in YAML = nil, want an error")
+	}
+}
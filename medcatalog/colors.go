@@ -0,0 +1,67 @@
+package medcatalog
+
+import (
+	"slices"
+)
+
+// colorClass is a card accent color, rendered as a .gradient-<name> class in colors.css
+type colorClass struct {
+	Name string
+	From string
+	To   string
+}
+
+// ColorClasses is the one place card colors are defined, colors.css is generated from it and a
+// product's color_class has to be one of these
+var ColorClasses = []colorClass{
+	{"blue", "#3b82f6", "#2563eb"},
+	{"indigo", "#6366f1", "#4f46e5"},
+	{"purple", "#a855f7", "#9333ea"},
+	{"pink", "#ec4899", "#db2777"},
+	{"teal", "#14b8a6", "#0d9488"},
+	{"emerald", "#10b981", "#059669"},
+	{"orange", "#f97316", "#ea580c"},
+	{"amber", "#f59e0b", "#d97706"},
+	{"cyan", "#06b6d4", "#0891b2"},
+	{"sky", "#0ea5e9", "#0284c7"},
+	{"red", "#ef4444", "#b91c1c"},
+	{"yellow", "#fde68a", "#f59e0b"},
+	{"lime", "#a3e635", "#65a30d"},
+	{"fuchsia", "#d946ef", "#a21caf"},
+	{"gray", "#e5e7eb", "#6b7280"},
+	{"stone", "#d6d3d1", "#78716c"},
+	{"violet", "#8b5cf6", "#7c3aed"},
+	{"green", "#22c55e", "#15803d"},
+	{"rose", "#f43f5e", "#be123c"},
+}
+
+// medicineTypeColors is the card color for each medicine type, a product's color comes from its
+// medicine type so every drug in a class looks the same. a catalog's config.json can add or change entries
+var medicineTypeColors = map[string]string{
+	"GLP-1":                   "gradient-indigo",
+	"SGLT-2":                  "gradient-teal",
+	"DPP-4":                   "gradient-sky",
+	"Insulin":                 "gradient-blue",
+	"Insulin Delivery System": "gradient-cyan",
+	"CGM":                     "gradient-emerald",
+}
+
+// defaultColorClass is used for medicine types without a color, e.g. ones added in enums.json
+const defaultColorClass = "gradient-gray"
+
+// derivedColorClass is the color class for the product's medicine type
+func (p Product) derivedColorClass() string {
+	if c, ok := p.Settings().medicineTypeColors[string(p.MedicineType)]; ok {
+		return c
+	}
+	return defaultColorClass
+}
+
+func (c colorClass) ClassName() string {
+	return "gradient-" + c.Name
+}
+
+// validColorClass reports whether name is the class of one of the defined colors
+func validColorClass(name string) bool {
+	return slices.ContainsFunc(ColorClasses, func(c colorClass) bool { return c.ClassName() == name })
+}
@@ -0,0 +1,109 @@
+package medcatalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+)
+
+// optional, in the catalog directory next to enums.json, the defaults are used when it doesn't exist
+const ConfigFile = "config.json"
+
+// Config is config.json, the additions and overrides a catalog makes to the built-in settings
+type Config struct {
+	// DeviceMedicineTypes extends deviceMedicineTypes, each one has to be a medicine type
+	DeviceMedicineTypes []string `json:"device_medicine_types,omitempty"`
+	// DeviceRoutes extends deviceRoutes, each one is also added as a valid administration route
+	DeviceRoutes []string `json:"device_routes,omitempty"`
+	// WeeklyInjectableExceptions extends weeklyInjectableExceptions
+	WeeklyInjectableExceptions []string `json:"weekly_injectable_exceptions,omitempty"`
+	// AffordableCopayCap overrides affordableCopayCap when set
+	AffordableCopayCap float64 `json:"affordable_copay_cap,omitempty"`
+	// MaxDescriptionLength overrides maxDescriptionLength when set
+	MaxDescriptionLength int `json:"max_description_length,omitempty"`
+	// MedicineTypeColors adds to or overrides medicineTypeColors
+	MedicineTypeColors map[string]string `json:"medicine_type_colors,omitempty"`
+	// SavingsLinkDomains extends savingsLinkDomains, links on other hosts are warned about
+	SavingsLinkDomains []string `json:"savings_link_domains,omitempty"`
+}
+
+// LoadConfig reads config.json from the catalog directory dir, LoadSettings applies it. unknown keys
+// are almost always typos, they fail like they do in catalog files
+func LoadConfig(dir string) (Config, error) {
+	var c Config
+	path := catalogPath(dir, ConfigFile)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	} else if err != nil {
+		return c, errors.Join(errors.New("failed reading "+path), err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&c); err != nil {
+		return c, errors.Join(errors.New("failed parsing JSON in "+path), err)
+	}
+	return c, nil
+}
+
+// apply extends the settings with the values from the config
+func (s *Settings) apply(c Config) error {
+	for _, medicineType := range c.DeviceMedicineTypes {
+		if err := s.medicineTypes.CheckError(medicineType); err != nil {
+			return fmt.Errorf("device_medicine_types in %s: %w", ConfigFile, err)
+		}
+		if !slices.Contains(s.deviceMedicineTypes, medicineType) {
+			s.deviceMedicineTypes = append(s.deviceMedicineTypes, medicineType)
+		}
+	}
+	for _, route := range c.DeviceRoutes {
+		if route == "" {
+			return fmt.Errorf("empty device route in %s", ConfigFile)
+		}
+		if !slices.Contains(s.adminRoutes, route) {
+			s.adminRoutes = append(s.adminRoutes, route)
+		}
+		if !slices.Contains(s.deviceRoutes, route) {
+			s.deviceRoutes = append(s.deviceRoutes, route)
+		}
+	}
+	if c.AffordableCopayCap < 0 {
+		return fmt.Errorf("affordable_copay_cap in %s can't be negative", ConfigFile)
+	} else if c.AffordableCopayCap > 0 {
+		s.affordableCopayCap = c.AffordableCopayCap
+	}
+	if c.MaxDescriptionLength < 0 {
+		return fmt.Errorf("max_description_length in %s can't be negative", ConfigFile)
+	} else if c.MaxDescriptionLength > 0 {
+		s.maxDescriptionLength = c.MaxDescriptionLength
+	}
+	for medicineType, class := range c.MedicineTypeColors {
+		if err := s.medicineTypes.CheckError(medicineType); err != nil {
+			return fmt.Errorf("medicine_type_colors in %s: %w", ConfigFile, err)
+		}
+		if !validColorClass(class) {
+			return fmt.Errorf("medicine_type_colors in %s: '%s' for '%s' isn't one of the colors defined in medcatalog/colors.go",
+				ConfigFile, class, medicineType)
+		}
+		s.medicineTypeColors[medicineType] = class
+	}
+	for _, domain := range c.SavingsLinkDomains {
+		domain = strings.TrimSuffix(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain == "" || strings.ContainsAny(domain, "/: ") {
+			return fmt.Errorf("savings_link_domains in %s: '%s' isn't a domain like lilly.com", ConfigFile, domain)
+		}
+		if !slices.Contains(s.savingsLinkDomains, domain) {
+			s.savingsLinkDomains = append(s.savingsLinkDomains, domain)
+		}
+	}
+	for _, brandName := range c.WeeklyInjectableExceptions {
+		if !slices.Contains(s.weeklyInjectableExceptions, brandName) {
+			s.weeklyInjectableExceptions = append(s.weeklyInjectableExceptions, brandName)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package medcatalog
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestConfigDeviceRoute(t *testing.T) {
+	pump := Product{BrandName: "Pumpco", IngredientName: "insulin pump", MedicineType: "Insulin Delivery System",
+		AdminRoute: "Manual Insulin Pump", DoseFrequency: "N/A", Savings: []SavingsInfo{testSavings("Pump on us")}}
+	if pump.Validate() == nil {
+		t.Fatal("product with an unknown administration route validated before the config added it")
+	}
+
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ConfigFile), `{"device_routes": ["Manual Insulin Pump"]}`)
+	settings, err := LoadSettings(dir)
+	if err != nil {
+		t.Fatalf("LoadSettings() failed: %v", err)
+	}
+	pump.settings = settings
+	if err := pump.Validate(); err != nil {
+		t.Errorf("device with the configured device route doesn't validate: %v", err)
+	}
+	for _, w := range pump.Warnings() {
+		if w.Rule == "missing_fda_label" {
+			t.Errorf("device with the configured device route is warned about its missing FDA label")
+		}
+	}
+	drug := pump
+	drug.MedicineType = "Insulin"
+	if drug.Validate() == nil {
+		t.Error("drug with the configured device route validated, device routes are only for devices")
+	}
+
+	srv := newFDATestServer(t, nil)
+	if _, err := EnrichFromFDA(ProductList{pump}, srv.options()); err != nil {
+		t.Fatalf("EnrichFromFDA() failed: %v", err)
+	}
+	if n := srv.requests.Load(); n != 0 {
+		t.Errorf("device with the configured device route was looked up, %d FDA request(s) made", n)
+	}
+}
+
+func TestLoadConfigFromCatalogDir(t *testing.T) {
+	dir := t.TempDir()
+	c, err := LoadConfig(dir)
+	if err != nil || c.AffordableCopayCap != 0 || c.DeviceRoutes != nil {
+		t.Fatalf("LoadConfig() without a config.json = %+v, %v, want the zero Config", c, err)
+	}
+	writeTestFile(t, filepath.Join(dir, ConfigFile), `{"affordable_copay_cap": 25, "device_routes": ["Manual Insulin Pump"]}`)
+	c, err = LoadConfig(dir)
+	if err != nil {
+		t.Fatalf("LoadConfig() failed: %v", err)
+	}
+	if c.AffordableCopayCap != 25 || !slices.Equal(c.DeviceRoutes, []string{"Manual Insulin Pump"}) {
+		t.Errorf("LoadConfig() = %+v, want the values from %s", c, ConfigFile)
+	}
+
+	writeTestFile(t, filepath.Join(dir, ConfigFile), `{"affordable_copay_cap": "25"}`)
+	if _, err = LoadConfig(dir); err == nil {
+		t.Error("LoadConfig() with a malformed config.json returned nil, want an error")
+	}
+
+	// a misspelled key would otherwise be silently ignored
+	writeTestFile(t, filepath.Join(dir, ConfigFile), `{"affordable_copay_capp": 25}`)
+	if _, err = LoadConfig(dir); err == nil {
+		t.Error("LoadConfig() with an unknown key in config.json returned nil, want an error")
+	}
+}
+
+func TestLoadSettingsPerCatalog(t *testing.T) {
+	product, err := os.ReadFile(filepath.Join("..", "testdata", "catalog", "glucozen.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	configured, plain := t.TempDir(), t.TempDir()
+	writeTestFile(t, filepath.Join(configured, ConfigFile), `{"affordable_copay_cap": 25, "device_routes": ["Manual Insulin Pump"]}`)
+	writeTestFile(t, filepath.Join(configured, "glucozen.json"), string(product))
+	writeTestFile(t, filepath.Join(plain, "glucozen.json"), string(product))
+
+	configuredProducts, err := Load(configured)
+	if err != nil {
+		t.Fatalf("Load(%s) failed: %v", configured, err)
+	}
+	plainProducts, err := Load(plain)
+	if err != nil {
+		t.Fatalf("Load(%s) failed: %v", plain, err)
+	}
+	if cap := configuredProducts[0].Settings().affordableCopayCap; cap != 25 {
+		t.Errorf("product from the configured catalog has copay cap %v, want 25", cap)
+	}
+	if cap := plainProducts[0].Settings().affordableCopayCap; cap != affordableCopayCap {
+		t.Errorf("product from the catalog without a config has copay cap %v, want the default %v", cap, affordableCopayCap)
+	}
+	if slices.Contains(plainProducts[0].Settings().deviceRoutes, "Manual Insulin Pump") ||
+		slices.Contains(defaultSettings.deviceRoutes, "Manual Insulin Pump") {
+		t.Error("device route configured for one catalog leaked into another")
+	}
+}
+
+func TestLoadPharmClassSnapshotFromCatalogDir(t *testing.T) {
+	dir := t.TempDir()
+	classes, err := LoadPharmClassSnapshot(dir)
+	if err != nil || classes != nil {
+		t.Fatalf("LoadPharmClassSnapshot() without a snapshot = %v, %v, want nil", classes, err)
+	}
+	writeTestFile(t, filepath.Join(dir, PharmClassSnapshotFile), `["GLP-1 Receptor Agonist [EPC]"]`)
+	classes, err = LoadPharmClassSnapshot(dir)
+	if err != nil || !slices.Equal(classes, []string{"GLP-1 Receptor Agonist [EPC]"}) {
+		t.Errorf("LoadPharmClassSnapshot() = %v, %v, want the pinned class", classes, err)
+	}
+}
+
+func TestLoadSkipsCatalogSettings(t *testing.T) {
+	dir := t.TempDir()
+	writeTestFile(t, filepath.Join(dir, ConfigFile), `{"affordable_copay_cap": 25}`)
+	writeTestFile(t, filepath.Join(dir, PharmClassSnapshotFile), `["GLP-1 Receptor Agonist [EPC]"]`)
+	product, err := os.ReadFile(filepath.Join("..", "testdata", "catalog", "glucozen.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestFile(t, filepath.Join(dir, "glucozen.json"), string(product))
+	products, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load() with config.json and pharmClasses.json in the catalog failed: %v", err)
+	}
+	if len(products) != 1 || products[0].BrandName != "Glucozen" {
+		t.Errorf("Load() = %+v, want only the Glucozen product", products)
+	}
+}
+
+func TestFDACacheDir(t *testing.T) {
+	srv := newFDATestServer(t, map[string][]fdaLabelResult{
+		exactBrandSearch("Glucozen"): {testLabel("Glucozen", "20250301")},
+	})
+	opts := srv.options()
+	opts.NoCache, opts.CacheTTL, opts.CacheDir = false, DefaultFDACacheTTL, t.TempDir()
+	products := ProductList{{BrandName: "Glucozen", IngredientName: "glucozide", MedicineType: "GLP-1",
+		AdminRoute: "Subcutaneous Injection"}}
+	if _, err := EnrichFromFDA(products, opts); err != nil {
+		t.Fatalf("EnrichFromFDA() failed: %v", err)
+	}
+	if entries, err := os.ReadDir(opts.CacheDir); err != nil || len(entries) == 0 {
+		t.Errorf("response wasn't cached in CacheDir: %v", err)
+	}
+	if _, err := EnrichFromFDA(products, opts); err != nil {
+		t.Fatalf("second EnrichFromFDA() failed: %v", err)
+	}
+	if n := srv.requests.Load(); n != 1 {
+		t.Errorf("%d FDA requests made, want 1 with the second lookup served from CacheDir", n)
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,48 @@
+package medcatalog
+
+import (
+	"fmt"
+	"strings"
+)
+
+// normalizeBrandName strips trademark symbols, collapses whitespace and lowercases a brand name so
+// "Ozempic®" and "ozempic" compare equal
+func normalizeBrandName(name string) string {
+	name = strings.NewReplacer("®", "", "™", "", "©", "", "℠", "").Replace(name)
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
+
+// findDuplicateBrands reports products that share a normalized brand name (and calls out when the
+// ingredient is the same too), naming both catalog files. the same brand can come in more than one
+// form (e.g. Wegovy as an injection and a pill) so the administration route is part of what makes a
+// product unique. slugs have to be unique outright since pages and the API are keyed by them.
+func findDuplicateBrands(products []Product) []error {
+	seen := map[string]Product{}
+	seenSlugs := map[string]Product{}
+	errs := []error{}
+	for _, p := range products {
+		if first, ok := seenSlugs[p.Slug]; ok {
+			errs = append(errs, ValidationError{File: p.sourceFile, Rule: "duplicate_slug", Err: fmt.Errorf(
+				"Failed: slug '%s' for product '%s' is already used by '%s' in %s, set a unique slug in one of them",
+				p.Slug, p.BrandName, first.BrandName, first.sourceFile)})
+		} else {
+			seenSlugs[p.Slug] = p
+		}
+
+		normalized := normalizeBrandName(p.BrandName)
+		key := normalized + "|" + string(p.AdminRoute)
+		first, ok := seen[key]
+		if !ok {
+			seen[key] = p
+			continue
+		}
+		sameIngredient := ""
+		if strings.EqualFold(strings.TrimSpace(p.IngredientName), strings.TrimSpace(first.IngredientName)) {
+			sameIngredient = fmt.Sprintf(" and the same ingredient name '%s'", p.IngredientName)
+		}
+		errs = append(errs, ValidationError{File: p.sourceFile, Rule: "duplicate_brand", Err: fmt.Errorf(
+			"Failed: brand name '%s' duplicates '%s' in %s (both normalize to '%s') with administration route '%s'%s",
+			p.BrandName, first.BrandName, first.sourceFile, normalized, p.AdminRoute, sameIngredient)})
+	}
+	return errs
+}
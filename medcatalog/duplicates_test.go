@@ -0,0 +1,26 @@
+package medcatalog
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFindDuplicateBrands(t *testing.T) {
+	ozempic := Product{BrandName: "Ozempic", Slug: "ozempic", IngredientName: "semaglutide", AdminRoute: "Subcutaneous Injection",
+		sourceFile: "catalog/ozempic.json"}
+	registered := Product{BrandName: "Ozempic®", Slug: "ozempic-registered", IngredientName: "semaglutide",
+		AdminRoute: "Subcutaneous Injection", sourceFile: "catalog/ozempic-registered.json"}
+
+	errs := findDuplicateBrands([]Product{ozempic, registered})
+	var verr ValidationError
+	if len(errs) != 1 || !errors.As(errs[0], &verr) || verr.Rule != "duplicate_brand" || verr.File != registered.sourceFile {
+		t.Fatalf("findDuplicateBrands() for 'Ozempic' and 'Ozempic®' = %v, want one duplicate_brand error for %s", errs, registered.sourceFile)
+	}
+
+	// the same brand as a pill is another product, not a duplicate
+	oral := registered
+	oral.AdminRoute = "Oral Tablet"
+	if errs := findDuplicateBrands([]Product{ozempic, oral}); len(errs) != 0 {
+		t.Errorf("findDuplicateBrands() for 'Ozempic' and 'Ozempic®' with different routes = %v, want none", errs)
+	}
+}
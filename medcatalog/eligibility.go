@@ -0,0 +1,56 @@
+package medcatalog
+
+import "time"
+
+// Insurance is how a patient pays for the prescription. a patient can have both private and
+// government coverage (e.g. an employer plan and Medicare), neither means they're uninsured
+type Insurance struct {
+	Private    bool
+	Government bool
+	// CashPay is set when the patient pays out of pocket, because they're uninsured or the drug
+	// isn't covered
+	CashPay bool
+	// IncomeFPLPercent is the household income as a percent of the federal poverty level, 0 when
+	// it isn't known and income limits aren't checked
+	IncomeFPLPercent int
+}
+
+// EligibleSavings returns the product's savings programs the patient could use, in the product's
+// order. the rules, first match wins:
+//
+//   - expired programs and ones whose income limit is under the patient's known income never match
+//   - government coverage comes first: federal rules bar copay cards for anyone on Medicare,
+//     Medicaid or other government insurance even when they also have a private plan, so those
+//     patients only match programs open to government insurance, or cash pay programs other than
+//     copay cards when they pay out of pocket
+//   - privately insured patients match programs open to private insurance, or to cash pay when
+//     they pay out of pocket
+//   - uninsured patients match programs open to cash pay
+//
+// programs with only other_criteria never match, there's nothing to match them on
+func (p Product) EligibleSavings(ins Insurance) []SavingsInfo {
+	eligible := []SavingsInfo{}
+	for _, s := range p.Savings {
+		if s.eligibleFor(ins) {
+			eligible = append(eligible, s)
+		}
+	}
+	return eligible
+}
+
+func (s SavingsInfo) eligibleFor(ins Insurance) bool {
+	e := s.Eligibility
+	if s.expired(time.Now()) || (ins.IncomeFPLPercent > 0 && e.IncomeLimitFPLPercent > 0 && ins.IncomeFPLPercent > e.IncomeLimitFPLPercent) {
+		return false
+	}
+	uninsured := !ins.Private && !ins.Government
+	cashPay := (ins.CashPay || uninsured) && e.CashPay
+	switch {
+	case ins.Government:
+		return e.GovernmentInsurance || (cashPay && s.Type != "Copay Discount Card")
+	case ins.Private:
+		return e.PrivateInsurance || cashPay
+	default:
+		return cashPay
+	}
+}
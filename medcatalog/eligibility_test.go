@@ -0,0 +1,67 @@
+package medcatalog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEligibleSavings(t *testing.T) {
+	copayCard := testSavings("Copay card")
+
+	government := SavingsInfo{Type: "Patient Assistance Program", Description: "Medicare assistance"}
+	government.Eligibility.GovernmentInsurance = true
+
+	cashPayCard := SavingsInfo{Type: "Copay Discount Card", Description: "Cash pay card"}
+	cashPayCard.Eligibility.CashPay = true
+
+	cashPayProgram := SavingsInfo{Type: "Patient Assistance Program", Description: "Cash pay program"}
+	cashPayProgram.Eligibility.CashPay = true
+
+	expired := testSavings("Expired card")
+	expired.Expires = time.Now().AddDate(0, -1, 0).Format("2006-01-02")
+
+	incomeLimited := SavingsInfo{Type: "Patient Assistance Program", Description: "Income limited program"}
+	incomeLimited.Eligibility.CashPay = true
+	incomeLimited.Eligibility.IncomeLimitFPLPercent = 400
+
+	tests := []struct {
+		name    string
+		savings SavingsInfo
+		ins     Insurance
+		want    bool
+	}{
+		{"government program, government insurance", government, Insurance{Government: true}, true},
+		{"government program, private insurance", government, Insurance{Private: true}, false},
+		{"copay card, government and private insurance", copayCard, Insurance{Government: true, Private: true}, false},
+		{"copay card, private insurance", copayCard, Insurance{Private: true}, true},
+		{"cash pay copay card, government insurance paying cash", cashPayCard, Insurance{Government: true, CashPay: true}, false},
+		{"cash pay program, government insurance paying cash", cashPayProgram, Insurance{Government: true, CashPay: true}, true},
+		{"cash pay program, government insurance not paying cash", cashPayProgram, Insurance{Government: true}, false},
+		{"cash pay program, private insurance paying cash", cashPayProgram, Insurance{Private: true, CashPay: true}, true},
+		{"cash pay program, private insurance not paying cash", cashPayProgram, Insurance{Private: true}, false},
+		{"cash pay program, uninsured", cashPayProgram, Insurance{}, true},
+		{"copay card, uninsured", copayCard, Insurance{}, false},
+		{"expired copay card, private insurance", expired, Insurance{Private: true}, false},
+		{"income limit, under it", incomeLimited, Insurance{IncomeFPLPercent: 250}, true},
+		{"income limit, at it", incomeLimited, Insurance{IncomeFPLPercent: 400}, true},
+		{"income limit, over it", incomeLimited, Insurance{IncomeFPLPercent: 450}, false},
+		{"income limit, income unknown", incomeLimited, Insurance{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Product{BrandName: "Glucozen", Savings: []SavingsInfo{tt.savings}}
+			got := p.EligibleSavings(tt.ins)
+			if (len(got) == 1) != tt.want {
+				t.Errorf("EligibleSavings(%+v) for %s = %v, want eligible %t", tt.ins, tt.savings.Description, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEligibleSavingsOrder(t *testing.T) {
+	p := Product{BrandName: "Glucozen", Savings: []SavingsInfo{testSavings("First card"), testSavings("Second card")}}
+	got := p.EligibleSavings(Insurance{Private: true})
+	if len(got) != 2 || got[0].Description != "First card" || got[1].Description != "Second card" {
+		t.Errorf("EligibleSavings() = %+v, want both programs in the product's order", got)
+	}
+}
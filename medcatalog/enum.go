@@ -0,0 +1,91 @@
+package medcatalog
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type enum []string
+
+func NewEnum(values []string) enum {
+	return enum(values)
+}
+
+var ErrInvalidEnumValue = errors.New("invalid value for enum")
+
+// CheckError returns an error wrapping ErrInvalidEnumValue that lists the recognized values when
+// value isn't one of them, every enum check reports it the same way
+func (e *enum) CheckError(value string) error {
+	if !e.Valid(value) {
+		if suggestion, ok := e.Suggest(value); ok {
+			return fmt.Errorf("%w: '%s' is not in the recognized list, did you mean '%s'?", ErrInvalidEnumValue, value, suggestion)
+		}
+		return fmt.Errorf("%w: '%s' is not in the recognized list (%s)", ErrInvalidEnumValue, value, strings.Join(*e, ", "))
+	}
+	return nil
+}
+
+// Suggest returns the value closest to the given one by case-insensitive edit distance, if it's close
+// enough to be a likely typo (a few edits, fewer for short values)
+func (e *enum) Suggest(value string) (string, bool) {
+	best, bestDistance := "", -1
+	lowerValue := strings.ToLower(value)
+	for _, v := range *e {
+		d := levenshtein(lowerValue, strings.ToLower(v))
+		threshold := max(1, min(3, len([]rune(v))/3))
+		if d <= threshold && (bestDistance < 0 || d < bestDistance) {
+			best, bestDistance = v, d
+		}
+	}
+	return best, bestDistance >= 0
+}
+
+// levenshtein counts the single rune insertions, deletions and substitutions to turn a into b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// Valid performs a case-sensitive check for validity
+func (e *enum) Valid(value string) bool {
+	for _, v := range *e {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidCI performs a case-insensitive check for validity
+func (e *enum) ValidCI(value string) bool {
+	lowerValue := strings.ToLower(value)
+	for _, v := range *e {
+		if strings.ToLower(v) == lowerValue {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *enum) MustValidate(value string) {
+	if err := e.CheckError(value); err != nil {
+		panic(err)
+	}
+}
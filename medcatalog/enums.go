@@ -0,0 +1,70 @@
+package medcatalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// optional, in the catalog directory, replaces the built-in enum values for any set it lists
+const enumsFile = "enums.json"
+
+type enumsConfig struct {
+	MedicineTypes   []string `json:"medicine_types,omitempty"`
+	AdminRoutes     []string `json:"administration_routes,omitempty"`
+	SavingsTypes    []string `json:"savings_types,omitempty"`
+	DoseFrequencies []string `json:"dose_frequencies,omitempty"`
+}
+
+// loadEnums replaces the settings' medicine types, administration routes, savings types and dose
+// frequencies with the sets in enums.json in the catalog directory dir, keeping the built-in values
+// for any set it leaves out or when the file doesn't exist
+func (s *Settings) loadEnums(dir string) error {
+	path := catalogPath(dir, enumsFile)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	} else if err != nil {
+		return errors.Join(errors.New("failed reading "+path), err)
+	}
+
+	var c enumsConfig
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err = dec.Decode(&c); err != nil {
+		return errors.Join(errors.New("failed parsing JSON in "+path+
+			", expected an object with medicine_types, administration_routes, savings_types and/or dose_frequencies lists"), err)
+	}
+
+	sets := []struct {
+		field  string
+		values []string
+		target *enum
+	}{
+		{"medicine_types", c.MedicineTypes, &s.medicineTypes},
+		{"administration_routes", c.AdminRoutes, &s.adminRoutes},
+		{"savings_types", c.SavingsTypes, &s.savingsTypes},
+		{"dose_frequencies", c.DoseFrequencies, &s.doseFrequencies},
+	}
+	for _, set := range sets {
+		if set.values == nil {
+			continue
+		}
+		for i, v := range set.values {
+			if v == "" {
+				return fmt.Errorf("empty value in %s in %s", set.field, path)
+			}
+			if slices.Contains(set.values[:i], v) {
+				return fmt.Errorf("duplicate value '%s' in %s in %s", v, set.field, path)
+			}
+		}
+		*set.target = NewEnum(set.values)
+	}
+	// the device lists can only name values that are still declared
+	s.deviceRoutes = slices.DeleteFunc(s.deviceRoutes, func(route string) bool { return !s.adminRoutes.Valid(route) })
+	s.deviceMedicineTypes = slices.DeleteFunc(s.deviceMedicineTypes, func(t string) bool { return !s.medicineTypes.Valid(t) })
+	return nil
+}
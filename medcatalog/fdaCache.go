@@ -0,0 +1,77 @@
+package medcatalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultFDACacheDir is where raw OpenFDA responses are cached (keyed by brand name) when
+// FDALookupOptions.CacheDir isn't set, relative to the working directory
+const DefaultFDACacheDir = ".fda-cache/"
+
+const DefaultFDACacheTTL = 24 * time.Hour
+
+// fdaCacheEntry keeps the whole raw response so more fields can be pulled out later without re-fetching
+type fdaCacheEntry struct {
+	BrandName string       `json:"brand_name"`
+	URL       string       `json:"url"`
+	FetchedAt time.Time    `json:"fetched_at"`
+	Data      fdaLabelData `json:"data"`
+}
+
+// cacheDir is the directory the lookup caches OpenFDA responses in
+func (opts FDALookupOptions) cacheDir() string {
+	if opts.CacheDir == "" {
+		return DefaultFDACacheDir
+	}
+	return opts.CacheDir
+}
+
+func fdaCachePath(brandName string, opts FDALookupOptions) string {
+	return filepath.Join(opts.cacheDir(), Slugify(brandName)+".json")
+}
+
+// readFDACache returns the cached response for the brand name if there is one younger than the TTL
+// that was made with the same search url
+func readFDACache(brandName string, u string, opts FDALookupOptions) (fdaLabelData, bool, error) {
+	if opts.NoCache {
+		return fdaLabelData{}, false, nil
+	}
+	content, err := os.ReadFile(fdaCachePath(brandName, opts))
+	if errors.Is(err, os.ErrNotExist) {
+		return fdaLabelData{}, false, nil
+	} else if err != nil {
+		return fdaLabelData{}, false, errors.Join(fmt.Errorf("failed reading FDA cache for %s", brandName), err)
+	}
+	var entry fdaCacheEntry
+	if err = json.Unmarshal(content, &entry); err != nil {
+		// a corrupt entry is just a miss, it'll be overwritten by the fresh response
+		logf(opts.Log, "Ignoring unreadable FDA cache entry for %s: %v\n", brandName, err)
+		return fdaLabelData{}, false, nil
+	}
+	if entry.URL != u || time.Since(entry.FetchedAt) > opts.CacheTTL {
+		return fdaLabelData{}, false, nil
+	}
+	return entry.Data, true, nil
+}
+
+func writeFDACache(brandName string, u string, data fdaLabelData, opts FDALookupOptions) error {
+	if opts.NoCache {
+		return nil
+	}
+	content, err := json.Marshal(fdaCacheEntry{BrandName: brandName, URL: u, FetchedAt: time.Now(), Data: data})
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed encoding FDA cache for %s", brandName), err)
+	}
+	if err = os.MkdirAll(opts.cacheDir(), 0o755); err != nil {
+		return errors.Join(errors.New("failed creating FDA cache directory"), err)
+	}
+	if err = os.WriteFile(fdaCachePath(brandName, opts), content, 0o644); err != nil {
+		return errors.Join(fmt.Errorf("failed writing FDA cache for %s", brandName), err)
+	}
+	return nil
+}
@@ -0,0 +1,789 @@
+package medcatalog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unicode"
+
+	"golang.org/x/time/rate"
+)
+
+type fdaLabelResult struct {
+	SplProductDataElements                               []string `json:"spl_product_data_elements"`
+	RecentMajorChanges                                   []string `json:"recent_major_changes"`
+	RecentMajorChangesTable                              []string `json:"recent_major_changes_table"`
+	BoxedWarning                                         []string `json:"boxed_warning"`
+	IndicationsAndUsage                                  []string `json:"indications_and_usage"`
+	DosageAndAdministration                              []string `json:"dosage_and_administration"`
+	DosageFormsAndStrengths                              []string `json:"dosage_forms_and_strengths"`
+	Contraindications                                    []string `json:"contraindications"`
+	WarningsAndCautions                                  []string `json:"warnings_and_cautions"`
+	AdverseReactions                                     []string `json:"adverse_reactions"`
+	AdverseReactionsTable                                []string `json:"adverse_reactions_table"`
+	DrugInteractions                                     []string `json:"drug_interactions"`
+	UseInSpecificPopulations                             []string `json:"use_in_specific_populations"`
+	Pregnancy                                            []string `json:"pregnancy"`
+	PediatricUse                                         []string `json:"pediatric_use"`
+	GeriatricUse                                         []string `json:"geriatric_use"`
+	Overdosage                                           []string `json:"overdosage"`
+	Description                                          []string `json:"description"`
+	ClinicalPharmacology                                 []string `json:"clinical_pharmacology"`
+	MechanismOfAction                                    []string `json:"mechanism_of_action"`
+	Pharmacodynamics                                     []string `json:"pharmacodynamics"`
+	Pharmacokinetics                                     []string `json:"pharmacokinetics"`
+	NonclinicalToxicology                                []string `json:"nonclinical_toxicology"`
+	CarcinogenesisAndMutagenesisAndImpairmentOfFertility []string `json:"carcinogenesis_and_mutagenesis_and_impairment_of_fertility"`
+	ClinicalStudies                                      []string `json:"clinical_studies"`
+	ClinicalStudiesTable                                 []string `json:"clinical_studies_table"`
+	HowSupplied                                          []string `json:"how_supplied"`
+	HowSuppliedTable                                     []string `json:"how_supplied_table"`
+	StorageAndHandling                                   []string `json:"storage_and_handling"`
+	InformationForPatients                               []string `json:"information_for_patients"`
+	SplMedguide                                          []string `json:"spl_medguide"`
+	SplMedguideTable                                     []string `json:"spl_medguide_table"`
+	InstructionsForUse                                   []string `json:"instructions_for_use"`
+	InstructionsForUseTable                              []string `json:"instructions_for_use_table"`
+	PackageLabelPrincipalDisplayPanel                    []string `json:"package_label_principal_display_panel"`
+	SetID                                                string   `json:"set_id"`
+	ID                                                   string   `json:"id"`
+	EffectiveTime                                        string   `json:"effective_time"`
+	Version                                              string   `json:"version"`
+	Openfda                                              struct {
+		ApplicationNumber  []string `json:"application_number"`
+		BrandName          []string `json:"brand_name"`
+		GenericName        []string `json:"generic_name"`
+		ManufacturerName   []string `json:"manufacturer_name"`
+		ProductNdc         []string `json:"product_ndc"`
+		ProductType        []string `json:"product_type"`
+		Route              []string `json:"route"`
+		SubstanceName      []string `json:"substance_name"`
+		Rxcui              []string `json:"rxcui"`
+		SplID              []string `json:"spl_id"`
+		SplSetID           []string `json:"spl_set_id"`
+		PackageNdc         []string `json:"package_ndc"`
+		IsOriginalPackager []bool   `json:"is_original_packager"`
+		Upc                []string `json:"upc"`
+		Nui                []string `json:"nui"`
+		PharmClassEpc      []string `json:"pharm_class_epc"`
+		PharmClassMoa      []string `json:"pharm_class_moa"`
+		Unii               []string `json:"unii"`
+	} `json:"openfda"`
+}
+
+type fdaLabelData struct {
+	Meta struct {
+		Disclaimer  string `json:"disclaimer"`
+		Terms       string `json:"terms"`
+		License     string `json:"license"`
+		LastUpdated string `json:"last_updated"`
+		Results     struct {
+			Skip  int `json:"skip"`
+			Limit int `json:"limit"`
+			Total int `json:"total"`
+		} `json:"results"`
+	} `json:"meta"`
+	Results []fdaLabelResult `json:"results"`
+}
+
+// ErrUnverifiedNDCs is returned by EnrichFromFDA with VerifyNDC when a product's NDCs aren't on any
+// of its matching labels, every label was still looked up
+var ErrUnverifiedNDCs = errors.New("NDCs not found on any matching FDA label")
+
+const fdaLabelAPIBase = "https://api.fda.gov/drug/label.json" // ?search=<brand_name>
+
+// results per OpenFDA request, and a cap on how many pages are read for one search so a query that
+// matches far too much can't run away
+const (
+	fdaPageSize = 30
+	fdaMaxPages = 10
+)
+const rateLimitSeconds = 2
+
+// fdaMinRequestInterval is the fastest the limiter is sped up to when OpenFDA's rate limit headers
+// show plenty of requests left, 240 a minute is OpenFDA's per-minute cap
+const fdaMinRequestInterval = time.Minute / 240
+
+// FDALookupOptions are the knobs for the FDA label lookup, set from the command line flags
+type FDALookupOptions struct {
+	// OverwriteRxCUIs replaces RxCUIs already set by hand with the ones from the matched label
+	OverwriteRxCUIs bool
+	// VerifyNDC requires every NDC stored on a product to be listed on one of its matching labels
+	VerifyNDC bool
+	// CacheTTL is how long a cached OpenFDA response is reused, NoCache skips the cache entirely
+	CacheTTL time.Duration
+	NoCache  bool
+	// CacheDir is where the responses are cached, DefaultFDACacheDir when empty
+	CacheDir string
+	// Timeout bounds each request to OpenFDA, including reading the response
+	Timeout time.Duration
+	// MaxRetries is how many times a 429 or 5xx response is retried
+	MaxRetries int
+	// Workers is how many brand names are looked up at once, they all share one rate limiter
+	Workers int
+	// QuotePhrases wraps multi-word brand names in quotes so OpenFDA searches the exact phrase
+	// instead of matching any of the words
+	QuotePhrases bool
+	// APIKey is sent as api_key with each request for OpenFDA's higher limits, it's never printed
+	APIKey string
+	// Client makes the OpenFDA requests, nil uses a plain http.Client. set one with its own
+	// Transport to serve canned responses (e.g. from an httptest.Server) instead of the real API
+	Client *http.Client
+	// Log gets the lookup's progress messages, they're dropped when it's nil
+	Log io.Writer
+
+	// metrics is where the lookup in progress counts its requests, nil when nothing is counting
+	metrics *fdaLookupMetrics
+}
+
+// fdaLookupMetrics accumulates what the lookup's requests cost, workers add to it concurrently
+type fdaLookupMetrics struct {
+	requests      atomic.Int64
+	retries       atomic.Int64
+	rateLimitWait atomic.Int64 // nanoseconds
+	networkTime   atomic.Int64 // nanoseconds
+}
+
+// httpClient is the client OpenFDA requests are made with
+func (opts FDALookupOptions) httpClient() *http.Client {
+	if opts.Client != nil {
+		return opts.Client
+	}
+	return &http.Client{}
+}
+
+// requestInterval is the spacing between FDA requests until OpenFDA's rate limit headers say
+// otherwise, requests with an API key start at the fastest rate
+func (opts FDALookupOptions) requestInterval() time.Duration {
+	if opts.APIKey != "" {
+		return fdaMinRequestInterval
+	}
+	return rateLimitSeconds * time.Second
+}
+
+// fdaLabelSearchURL builds the loose full-text OpenFDA query for a brand name
+func fdaLabelSearchURL(brandName string, opts FDALookupOptions) string {
+	u, _ := url.Parse(fdaLabelAPIBase)
+	q := u.Query()
+	search := brandName
+	if opts.QuotePhrases && strings.ContainsFunc(brandName, unicode.IsSpace) {
+		search = `"` + brandName + `"`
+	}
+	q.Set("search", search)
+	q.Set("limit", strconv.Itoa(fdaPageSize))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// fdaExactBrandSearchURL builds an OpenFDA query that only matches labels with exactly this brand name
+func fdaExactBrandSearchURL(brandName string) string {
+	u, _ := url.Parse(fdaLabelAPIBase)
+	q := u.Query()
+	q.Set("search", `openfda.brand_name.exact:"`+brandName+`"`)
+	q.Set("limit", strconv.Itoa(fdaPageSize))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// fdaGenericSearchURL builds the OpenFDA query for labels with the given generic name, used when a
+// brand name search doesn't turn up the brand's label
+func fdaGenericSearchURL(ingredientName string) string {
+	u, _ := url.Parse(fdaLabelAPIBase)
+	q := u.Query()
+	q.Set("search", `openfda.generic_name:"`+ingredientName+`"`)
+	q.Set("limit", strconv.Itoa(fdaPageSize))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// fdaNDCSearchURL builds an OpenFDA query for the labels listing this product NDC, used instead of
+// the brand name searches for products whose brand name also matches other formulations' labels
+func fdaNDCSearchURL(productNDC string) string {
+	u, _ := url.Parse(fdaLabelAPIBase)
+	q := u.Query()
+	q.Set("search", `openfda.product_ndc:"`+productNDC+`"`)
+	q.Set("limit", strconv.Itoa(fdaPageSize))
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// fdaLabelQuery is what's looked up for one brand, the ingredient name is the fallback search.
+// when ProductNDC is set it's the only search
+type fdaLabelQuery struct {
+	BrandName      string
+	IngredientName string
+	ProductNDC     string
+	// AdminRoutes are the routes of the products sharing the query, a label found by generic name
+	// has to be for one of them
+	AdminRoutes []string
+}
+
+// key identifies the query's result, the same brand can be looked up under different product NDCs
+func (q fdaLabelQuery) key() string {
+	if q.ProductNDC == "" {
+		return q.BrandName
+	}
+	return q.BrandName + " ndc " + q.ProductNDC
+}
+
+// fdaLabelMatch is the most recent FDA label found for a brand name, a zero EffectiveTime means
+// we checked but found no matching label
+type fdaLabelMatch struct {
+	EffectiveTime time.Time
+	Result        fdaLabelResult
+	// AllResults holds every label that matched the brand name, not just the most recent one
+	AllResults []fdaLabelResult
+	FromCache  bool
+	// Strategy is the search that found the label, "product NDC", "exact brand name", "brand name" or "generic name"
+	Strategy string
+}
+
+// fdaLabelRecencyLookup looks up the most recent FDA label for each query, keyed by the query's key.
+// it's up to the caller to compare the label's effective date with the one the catalog recorded.
+// brand names are looked up by a pool of opts.Workers goroutines sharing one rate limiter, the first
+// error cancels the rest.
+func fdaLabelRecencyLookup(queries []fdaLabelQuery, opts FDALookupOptions) (map[string]fdaLabelMatch, error) {
+	workers := max(opts.Workers, 1)
+	logf(opts.Log, "starting FDA label recency lookup for %d brand names with %d workers\n", len(queries), workers)
+	logf(opts.Log, "network will take up to %s for rate limiting.\n", opts.requestInterval()*time.Duration(len(queries)))
+	l := rate.NewLimiter(rate.Every(opts.requestInterval()), workers)
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(nil)
+	jobs := make(chan fdaLabelQuery)
+	results := make(map[string]fdaLabelMatch)
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for q := range jobs {
+				match, err := lookupBrandLabel(ctx, l, q, opts)
+				if err != nil {
+					cancel(err)
+					return
+				}
+				mu.Lock()
+				results[q.key()] = match
+				mu.Unlock()
+			}
+		}()
+	}
+feed:
+	for _, q := range queries {
+		select {
+		case jobs <- q:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	if err := context.Cause(ctx); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// fdaSearchStrategy is one way of searching OpenFDA for a brand's labels
+type fdaSearchStrategy struct {
+	name     string
+	cacheKey string
+	url      string
+	// matches reports whether a result is for the product, brand name searches check the label's brand
+	// name and the generic name search the ingredient and route
+	matches func(fdaLabelResult) bool
+}
+
+// lookupBrandLabel finds the most recent FDA label for one brand name, it's safe to call concurrently.
+// the exact brand_name query is tried first, then the loose full-text search in case the label's
+// brand name is cased or worded differently, then the ingredient name since some labels are only
+// indexed under their generic name (those are matched on the ingredient and route, not the brand).
+// a product with a product NDC is only searched by that NDC, its brand name is ambiguous so the
+// heuristics could pick another formulation's label.
+func lookupBrandLabel(ctx context.Context, l *rate.Limiter, q fdaLabelQuery, opts FDALookupOptions) (fdaLabelMatch, error) {
+	brandName := q.BrandName
+	brandMatches := brandLabelMatcher(brandName)
+	// each strategy is cached under its own key so they don't overwrite each other
+	strategies := []fdaSearchStrategy{
+		{"exact brand name", brandName + " exact", fdaExactBrandSearchURL(brandName), brandMatches},
+		{"brand name", brandName, fdaLabelSearchURL(brandName, opts), brandMatches},
+	}
+	if strings.TrimSpace(q.IngredientName) != "" {
+		strategies = append(strategies, fdaSearchStrategy{"generic name", brandName + " generic", fdaGenericSearchURL(q.IngredientName),
+			genericLabelMatcher(q.IngredientName, q.AdminRoutes)})
+	}
+	if q.ProductNDC != "" {
+		strategies = []fdaSearchStrategy{
+			{"product NDC", q.key(), fdaNDCSearchURL(q.ProductNDC), func(result fdaLabelResult) bool {
+				return slices.Contains(result.Openfda.ProductNdc, q.ProductNDC)
+			}},
+		}
+	}
+
+	var match fdaLabelMatch
+	var fdaLabel fdaLabelData
+	var status, u string
+	fromCache := true
+	for _, strategy := range strategies {
+		var cached bool
+		var err error
+		u = strategy.url
+		if strategy.name == "brand name" && opts.QuotePhrases && strings.ContainsFunc(brandName, unicode.IsSpace) {
+			logf(opts.Log, "Searching FDA labels for the exact phrase %q\n", brandName)
+		}
+		fdaLabel, status, cached, err = searchFDALabels(ctx, l, brandName, strategy.cacheKey, u, opts)
+		if err != nil {
+			return fdaLabelMatch{}, err
+		}
+		fromCache = fromCache && cached
+		if match, err = matchBrandLabel(brandName, u, fdaLabel, strategy.matches, opts.Log); err != nil {
+			return match, err
+		}
+		if !match.EffectiveTime.IsZero() {
+			match.Strategy = strategy.name
+			break
+		}
+	}
+	match.FromCache = fromCache
+
+	switch {
+	case len(fdaLabel.Results) == 0:
+		logf(opts.Log, "Checked FDA label for brand name: %s status %s ... no FDA label results found, URL: %s\n", brandName, status, u)
+	case match.EffectiveTime.IsZero():
+		logf(opts.Log, "Checked FDA label for brand name: %s status %s ... no valid results found.\n", brandName, status)
+	default:
+		logf(opts.Log, "Checked FDA label for brand name: %s status %s ... found by %s search.\n", brandName, status, match.Strategy)
+	}
+	return match, nil
+}
+
+// searchFDALabels returns the OpenFDA response for the search url, from the cache (under cacheKey)
+// when there's a fresh one
+func searchFDALabels(ctx context.Context, l *rate.Limiter, brandName, cacheKey, u string, opts FDALookupOptions) (fdaLabelData, string, bool, error) {
+	fdaLabel, fromCache, err := readFDACache(cacheKey, u, opts)
+	if err != nil {
+		return fdaLabel, "", false, err
+	}
+	if fromCache {
+		return fdaLabel, "cached", true, nil
+	}
+	fdaLabel, status, err := fetchFDALabelPages(ctx, l, u, opts)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fdaLabel, status, false, fmt.Errorf("FDA label lookup for brand name %s timed out after %s: %w", brandName, opts.Timeout, err)
+	} else if err != nil {
+		return fdaLabel, status, false, fmt.Errorf("FDA label lookup for brand name %s failed: %w", brandName, err)
+	}
+	if err = writeFDACache(cacheKey, u, fdaLabel, opts); err != nil {
+		return fdaLabel, status, false, err
+	}
+	return fdaLabel, status, false, nil
+}
+
+// fetchFDALabelData makes the OpenFDA request for the search url, returning the decoded response
+// and its status. 429s and 5xxs are retried up to opts.MaxRetries times with exponential backoff.
+func fetchFDALabelData(ctx context.Context, l *rate.Limiter, u string, opts FDALookupOptions) (fdaLabelData, string, error) {
+	for attempt := 0; ; attempt++ {
+		fdaLabel, status, retryAfter, err := fetchFDALabelDataOnce(ctx, l, u, opts)
+		if err == nil || retryAfter < 0 || attempt >= opts.MaxRetries {
+			return fdaLabel, status, err
+		}
+		wait := max(retryAfter, fdaRetryBackoff(attempt))
+		if opts.metrics != nil {
+			opts.metrics.retries.Add(1)
+		}
+		logf(opts.Log, "Retrying FDA API request in %s (attempt %d of %d): %v\n", wait.Round(time.Millisecond), attempt+2, opts.MaxRetries+1, err)
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return fdaLabel, status, context.Cause(ctx)
+		}
+	}
+}
+
+// fetchFDALabelPages fetches every page of results for the search url (up to fdaMaxPages) using the
+// skip parameter, returning them together as one response. each page waits on the rate limiter
+func fetchFDALabelPages(ctx context.Context, l *rate.Limiter, u string, opts FDALookupOptions) (fdaLabelData, string, error) {
+	all, status, err := fetchFDALabelData(ctx, l, u, opts)
+	if err != nil {
+		return all, status, err
+	}
+	total := all.Meta.Results.Total
+	for page := 1; len(all.Results) < total && page < fdaMaxPages; page++ {
+		pageURL, err := url.Parse(u)
+		if err != nil {
+			return all, status, errors.Join(errors.New("failed parsing FDA search url "+u), err)
+		}
+		q := pageURL.Query()
+		q.Set("skip", strconv.Itoa(page*fdaPageSize))
+		pageURL.RawQuery = q.Encode()
+		data, pageStatus, err := fetchFDALabelData(ctx, l, pageURL.String(), opts)
+		if err != nil {
+			return all, pageStatus, fmt.Errorf("failed fetching page %d of FDA results: %w", page+1, err)
+		}
+		if len(data.Results) == 0 {
+			break
+		}
+		all.Results = append(all.Results, data.Results...)
+	}
+	if len(all.Results) < total {
+		logf(opts.Log, "Only read %d of %d FDA label results for %s\n", len(all.Results), total, u)
+	}
+	return all, status, nil
+}
+
+const fdaRetryBaseDelay = time.Second
+
+// fdaRetryBackoff doubles the delay each attempt with up to 50% jitter so concurrent workers don't
+// retry in lockstep
+func fdaRetryBackoff(attempt int) time.Duration {
+	d := fdaRetryBaseDelay << attempt
+	return d + time.Duration(rand.Int64N(int64(d/2)+1))
+}
+
+// fetchFDALabelDataOnce makes a single request. retryAfter is negative when the error isn't worth
+// retrying, otherwise it's how long the server asked us to wait (0 if it didn't say).
+// the API key is only added to the url that's requested, u (which is printed) never has it.
+func fetchFDALabelDataOnce(ctx context.Context, l *rate.Limiter, u string, opts FDALookupOptions) (fdaLabel fdaLabelData, status string, retryAfter time.Duration, err error) {
+	waitStart := time.Now()
+	if err := l.Wait(ctx); err != nil {
+		return fdaLabel, "", -1, fmt.Errorf("error waiting for rate limiter: %w", err)
+	}
+	if opts.metrics != nil {
+		opts.metrics.rateLimitWait.Add(int64(time.Since(waitStart)))
+		opts.metrics.requests.Add(1)
+		// the network time runs until the response has been read, however it ends
+		requestStart := time.Now()
+		defer func() { opts.metrics.networkTime.Add(int64(time.Since(requestStart))) }()
+	}
+	// the timeout starts after the rate limiter so waiting our turn doesn't count against it
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	c := opts.httpClient()
+	req, err := http.NewRequestWithContext(ctx, "GET", u, nil)
+	if err != nil {
+		return fdaLabel, "", -1, fmt.Errorf("error building FDA API request: %w", err)
+	}
+	if opts.APIKey != "" {
+		q := req.URL.Query()
+		q.Set("api_key", opts.APIKey)
+		req.URL.RawQuery = q.Encode()
+	}
+	req.Header.Set("User-Agent", "pugnare.health/1.0")
+
+	resp, err := c.Do(req)
+	if err != nil {
+		// the client's error has the requested url in it, swap in the one without the key
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			urlErr.URL = u
+		}
+		return fdaLabel, "", -1, fmt.Errorf("error making FDA API request: %w", err)
+	}
+	// always close the body no matter which branch returns, only surfacing a close error if nothing else failed
+	defer func() {
+		if closeErr := resp.Body.Close(); closeErr != nil && err == nil {
+			retryAfter = -1
+			err = fmt.Errorf("error closing FDA API response body: %w", closeErr)
+		}
+	}()
+
+	adaptFDARateLimit(l, resp.Header, opts.requestInterval())
+
+	if resp.StatusCode != http.StatusOK {
+		err = fmt.Errorf("FDA API returned non-200 status (%d) url: %s", resp.StatusCode, u)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return fdaLabel, resp.Status, parseRetryAfter(resp.Header.Get("Retry-After")), err
+		}
+		return fdaLabel, resp.Status, -1, err
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&fdaLabel); err != nil {
+		return fdaLabel, "", -1, fmt.Errorf("failed to decode api json response: %w", err)
+	}
+	return fdaLabel, resp.Status, 0, nil
+}
+
+// parseRetryAfter reads a Retry-After header in either seconds or HTTP date form, 0 if missing or bad
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(header); err == nil {
+		return max(time.Until(t), 0)
+	}
+	return 0
+}
+
+// brandLabelMatcher matches labels for the brand name. the loose search returns other brands too, so the label's openfda brand name has to match (ignoring case and trademark symbols)
+func brandLabelMatcher(brandName string) func(fdaLabelResult) bool {
+	normalized := normalizeBrandName(brandName)
+	return func(result fdaLabelResult) bool {
+		return slices.ContainsFunc(result.Openfda.BrandName, func(b string) bool { return normalizeBrandName(b) == normalized })
+	}
+}
+
+// genericLabelMatcher matches labels found by the generic name search, which are indexed under the
+// ingredient rather than our brand name. the label's generic or substance name has to be the
+// ingredient, and it has to be for one of the routes so a pill's label isn't taken for an injection.
+// routes labels don't list (see labelRoutes) can't be told apart, so nothing matches for them
+func genericLabelMatcher(ingredientName string, adminRoutes []string) func(fdaLabelResult) bool {
+	normalized := normalizeBrandName(ingredientName)
+	routes := []string{}
+	for _, r := range adminRoutes {
+		if route, ok := labelRoutes[r]; ok {
+			routes = append(routes, route)
+		}
+	}
+	isIngredient := func(name string) bool { return normalizeBrandName(name) == normalized }
+	return func(result fdaLabelResult) bool {
+		return (slices.ContainsFunc(result.Openfda.GenericName, isIngredient) || slices.ContainsFunc(result.Openfda.SubstanceName, isIngredient)) &&
+			slices.ContainsFunc(result.Openfda.Route, func(r string) bool {
+				return slices.ContainsFunc(routes, func(route string) bool { return strings.EqualFold(strings.TrimSpace(r), route) })
+			})
+	}
+}
+
+// adaptFDARateLimit sets the limiter's spacing from OpenFDA's X-RateLimit-Remaining and
+// X-RateLimit-Limit headers, scaling from the fixed rateLimitSeconds when none of the budget is left
+// down to fdaMinRequestInterval when all of it is. without the headers it's the fallback spacing.
+func adaptFDARateLimit(l *rate.Limiter, header http.Header, fallback time.Duration) {
+	interval := fallback
+	limit, limitErr := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	remaining, remainingErr := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if limitErr == nil && remainingErr == nil && limit > 0 {
+		slowest := rateLimitSeconds * time.Second
+		headroom := float64(min(max(remaining, 0), limit)) / float64(limit)
+		interval = slowest - time.Duration(headroom*float64(slowest-fdaMinRequestInterval))
+	}
+	l.SetLimit(rate.Every(interval))
+}
+
+// matchBrandLabel picks the most recent label in the response that matches, a zero EffectiveTime
+// means there wasn't one. OpenFDA has some labels with a blank or malformed effective time, those
+// are skipped, it's only an error when they're the only labels that matched
+func matchBrandLabel(brandName string, u string, fdaLabel fdaLabelData, matches func(fdaLabelResult) bool, log io.Writer) (fdaLabelMatch, error) {
+	lastChecked := fdaLabelMatch{}
+	allMatched := []fdaLabelResult{}
+	var parseErr error
+	for _, result := range fdaLabel.Results {
+		if !matches(result) {
+			continue
+		}
+		allMatched = append(allMatched, result)
+		effectiveTime, err := time.Parse("20060102", result.EffectiveTime)
+		if err != nil {
+			logf(log, "Skipping FDA label for %s with unparseable effective time %q\n", brandName, result.EffectiveTime)
+			parseErr = err
+			continue
+		}
+		if effectiveTime.After(lastChecked.EffectiveTime) {
+			lastChecked = fdaLabelMatch{EffectiveTime: effectiveTime, Result: result}
+		}
+	}
+	if lastChecked.EffectiveTime.IsZero() {
+		if parseErr != nil {
+			return fdaLabelMatch{}, fmt.Errorf("error parsing effective time from every matching FDA label for %s (%s): %w", brandName, u, parseErr)
+		}
+		return fdaLabelMatch{}, nil
+	}
+	lastChecked.AllResults = allMatched
+	return lastChecked, nil
+}
+
+type ProductList []Product
+
+// FDALookupStats counts what the FDA label lookup did, for the run summary and build report
+type FDALookupStats struct {
+	// Lookups is how many brands were looked up, CacheHits of them entirely from the cache
+	Lookups     int
+	CacheHits   int
+	CacheMisses int
+	// Requests is how many requests were made to OpenFDA, Retries of them repeats after a 429 or 5xx
+	Requests int
+	Retries  int
+	// RateLimitWait and NetworkTime add up every request's wait for the rate limiter and time on
+	// the network, across workers, so with several workers they can be more than WallTime
+	RateLimitWait time.Duration
+	NetworkTime   time.Duration
+	WallTime      time.Duration
+}
+
+func (s FDALookupStats) String() string {
+	return fmt.Sprintf("FDA lookup: %d brand(s), %d from cache, %d not, %d request(s), %d retried, %s waiting on the rate limiter, %s on the network, %s total",
+		s.Lookups, s.CacheHits, s.CacheMisses, s.Requests, s.Retries,
+		s.RateLimitWait.Round(time.Millisecond), s.NetworkTime.Round(time.Millisecond), s.WallTime.Round(time.Millisecond))
+}
+
+// EnrichFromFDA looks up each drug's newest FDA label, marking products whose label has changed since
+// it was last recorded and filling in identifiers and the boxed warning from the matched label.
+func EnrichFromFDA(list ProductList, opts FDALookupOptions) (FDALookupStats, error) {
+	queryKeys := []string{}
+	queries := []fdaLabelQuery{}
+	for _, p := range list {
+		// the same brand can be listed more than once (e.g. as an injection and a pill), only look it up once
+		q := fdaLabelQuery{BrandName: p.BrandName, IngredientName: p.IngredientName, ProductNDC: p.ProductNDC}
+		if p.SkipFDALookup() {
+			continue
+		}
+		if i := slices.Index(queryKeys, q.key()); i >= 0 {
+			if !slices.Contains(queries[i].AdminRoutes, string(p.AdminRoute)) {
+				queries[i].AdminRoutes = append(queries[i].AdminRoutes, string(p.AdminRoute))
+			}
+			continue
+		}
+		q.AdminRoutes = []string{string(p.AdminRoute)}
+		queryKeys = append(queryKeys, q.key())
+		queries = append(queries, q)
+	}
+
+	metrics := &fdaLookupMetrics{}
+	opts.metrics = metrics
+	start := time.Now()
+	recencyResults, err := fdaLabelRecencyLookup(queries, opts)
+	if err != nil {
+		return FDALookupStats{}, errors.Join(errors.New("error looking up FDA label recency"), err)
+	}
+	stats := FDALookupStats{
+		Lookups:       len(recencyResults),
+		Requests:      int(metrics.requests.Load()),
+		Retries:       int(metrics.retries.Load()),
+		RateLimitWait: time.Duration(metrics.rateLimitWait.Load()),
+		NetworkTime:   time.Duration(metrics.networkTime.Load()),
+		WallTime:      time.Since(start),
+	}
+	for _, match := range recencyResults {
+		if match.FromCache {
+			stats.CacheHits++
+		} else {
+			stats.CacheMisses++
+		}
+	}
+
+	// print out the results
+	unverifiedNDCs := []string{}
+	for i, p := range list {
+		key := fdaLabelQuery{BrandName: p.BrandName, ProductNDC: p.ProductNDC}.key()
+		if !slices.Contains(queryKeys, key) {
+			continue // skip products we didn't check
+		}
+		match, ok := recencyResults[key]
+		if !ok {
+			return stats, fmt.Errorf("%s: no FDA label recency found for brand name: %s", p.sourceFile, p.BrandName)
+		}
+		recency := match.EffectiveTime
+		if opts.VerifyNDC && len(p.NDCs) > 0 {
+			if missing := ndcsNotOnLabels(p.NDCs, match.AllResults); len(missing) > 0 {
+				logf(opts.Log, "NDCs for %s not found on any matching FDA label: %v\n", p.BrandName, missing)
+				unverifiedNDCs = append(unverifiedNDCs, fmt.Sprintf("%s %v", p.BrandName, missing))
+			}
+		}
+		if expected, ok := medicineTypePharmClasses[string(p.MedicineType)]; ok && len(match.Result.Openfda.PharmClassEpc) > 0 &&
+			!slices.ContainsFunc(match.Result.Openfda.PharmClassEpc, func(c string) bool { return slices.Contains(expected, c) }) {
+			list[i].fdaWarnings = append(list[i].fdaWarnings, Warning{"pharm_class_mismatch", fmt.Sprintf(
+				"FDA label has pharm classes %v, none of which match medicine type '%s', please verify",
+				match.Result.Openfda.PharmClassEpc, p.MedicineType)})
+		}
+		// identifiers only come from a label for this formulation, and manufacturers change hands so
+		// it's the newest one rather than any match
+		if label, ok := formulationLabel(p, match.AllResults); ok {
+			if len(label.Openfda.ManufacturerName) > 0 {
+				list[i].Manufacturer = strings.TrimSpace(label.Openfda.ManufacturerName[0])
+			}
+			if len(label.Openfda.ApplicationNumber) > 0 {
+				list[i].FDAApplicationNumber = strings.TrimSpace(label.Openfda.ApplicationNumber[0])
+			}
+			if len(label.BoxedWarning) > 0 {
+				list[i].BoxedWarning = strings.TrimSpace(strings.Join(label.BoxedWarning, "\n"))
+			}
+			if len(label.Openfda.Rxcui) > 0 && (len(p.RxCUIs) == 0 || opts.OverwriteRxCUIs) {
+				list[i].RxCUIs = label.Openfda.Rxcui
+			}
+		}
+		if recency.IsZero() {
+			logf(opts.Log, "No valid FDA label found for %s. Marking as not found.\n", p.BrandName)
+			list[i].FDALabelRecencyNotFound = true
+		} else {
+			list[i].FDALabelLatest = recency.Format("2006-01-02")
+		}
+		if strings.TrimSpace(p.FDALabelUpdated) == "" {
+			// nothing stored to compare against (e.g. a newly added drug), so whatever the FDA has is newer
+			logf(opts.Log, "No prior FDA label updated date for %s. Marking as needing update.\n", p.BrandName)
+			list[i].FDALabelNeedsUpdate = true
+			continue
+		}
+		lastUpdated, err := time.Parse("2006-01-02", p.FDALabelUpdated)
+		if err != nil {
+			return stats, errors.Join(fmt.Errorf("%s: error parsing existing FDA label updated date for %s: %v", p.sourceFile, p.BrandName, err), err)
+		}
+		if recency.After(lastUpdated) {
+			list[i].FDALabelNeedsUpdate = true
+		}
+	}
+	if len(unverifiedNDCs) > 0 {
+		return stats, fmt.Errorf("%w: %s", ErrUnverifiedNDCs, strings.Join(unverifiedNDCs, ", "))
+	}
+	return stats, nil
+}
+
+// labelRoutes is the openfda.route a label lists for each administration route, products on other
+// routes (devices, or routes added by config) don't take identifiers from labels
+var labelRoutes = map[string]string{
+	"Oral Tablet":            "ORAL",
+	"Subcutaneous Injection": "SUBCUTANEOUS",
+}
+
+// formulationLabel picks the newest of the brand's labels that's for the product's formulation, one
+// listing its product NDC when it has one, otherwise one for its route. a brand can be sold as both
+// an injection and a tablet, and only the matching label has the right RxCUIs, boxed warning,
+// manufacturer and application number
+func formulationLabel(p Product, labels []fdaLabelResult) (fdaLabelResult, bool) {
+	route, knownRoute := labelRoutes[string(p.AdminRoute)]
+	var newest fdaLabelResult
+	var newestTime time.Time
+	for _, l := range labels {
+		matches := knownRoute && slices.ContainsFunc(l.Openfda.Route, func(r string) bool {
+			return strings.EqualFold(strings.TrimSpace(r), route)
+		})
+		if p.ProductNDC != "" {
+			matches = slices.Contains(l.Openfda.ProductNdc, p.ProductNDC)
+		}
+		effectiveTime, err := time.Parse("20060102", l.EffectiveTime)
+		if matches && err == nil && effectiveTime.After(newestTime) {
+			newest, newestTime = l, effectiveTime
+		}
+	}
+	return newest, !newestTime.IsZero()
+}
+
+// ndcsNotOnLabels returns the NDCs that aren't listed as a product or package NDC on any of the labels
+func ndcsNotOnLabels(ndcs []string, labels []fdaLabelResult) []string {
+	missing := []string{}
+	for _, ndc := range ndcs {
+		found := slices.ContainsFunc(labels, func(l fdaLabelResult) bool {
+			return slices.Contains(l.Openfda.ProductNdc, ndc) || slices.Contains(l.Openfda.PackageNdc, ndc)
+		})
+		if !found {
+			missing = append(missing, ndc)
+		}
+	}
+	return missing
+}
@@ -0,0 +1,296 @@
+package medcatalog
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+// fdaTestServer answers OpenFDA label searches with canned results keyed by the search parameter,
+// searches it has nothing for get an empty result set
+type fdaTestServer struct {
+	*httptest.Server
+	requests atomic.Int64
+}
+
+func newFDATestServer(t *testing.T, results map[string][]fdaLabelResult) *fdaTestServer {
+	t.Helper()
+	s := &fdaTestServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		var data fdaLabelData
+		data.Results = results[r.URL.Query().Get("search")]
+		data.Meta.Results.Total = len(data.Results)
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(data); err != nil {
+			t.Errorf("failed writing canned FDA response: %v", err)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// options sends every OpenFDA request to the test server, without the cache
+func (s *fdaTestServer) options() FDALookupOptions {
+	target, _ := url.Parse(s.URL)
+	return FDALookupOptions{
+		NoCache: true,
+		Workers: 8,
+		Client:  &http.Client{Transport: redirectTransport{target: target, base: s.Client().Transport}},
+	}
+}
+
+// redirectTransport sends requests to target whatever host they were made for
+type redirectTransport struct {
+	target *url.URL
+	base   http.RoundTripper
+}
+
+func (rt redirectTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme, r.URL.Host = rt.target.Scheme, rt.target.Host
+	return rt.base.RoundTrip(r)
+}
+
+func exactBrandSearch(brandName string) string {
+	return `openfda.brand_name.exact:"` + brandName + `"`
+}
+
+func testLabel(brandName, effectiveTime string) fdaLabelResult {
+	var l fdaLabelResult
+	l.EffectiveTime = effectiveTime
+	l.Openfda.BrandName = []string{brandName}
+	return l
+}
+
+func TestEnrichFromFDAWithoutRecordedDate(t *testing.T) {
+	srv := newFDATestServer(t, map[string][]fdaLabelResult{
+		exactBrandSearch("Glucozen"): {testLabel("Glucozen", "20250301")},
+	})
+	products := ProductList{{BrandName: "Glucozen", IngredientName: "glucozide", MedicineType: "GLP-1",
+		AdminRoute: "Subcutaneous Injection"}}
+	if _, err := EnrichFromFDA(products, srv.options()); err != nil {
+		t.Fatalf("EnrichFromFDA() with no recorded date failed: %v", err)
+	}
+	if !products[0].FDALabelNeedsUpdate {
+		t.Error("product without a recorded label date isn't marked as needing an update")
+	}
+	if products[0].FDALabelLatest != "2025-03-01" {
+		t.Errorf("FDALabelLatest = %q, want 2025-03-01", products[0].FDALabelLatest)
+	}
+}
+
+func TestEnrichFromFDAIdentifiers(t *testing.T) {
+	injection := testLabel("Glucozen", "20250301")
+	injection.Openfda.Route = []string{"SUBCUTANEOUS"}
+	injection.Openfda.Rxcui = []string{"1991302", "1991306"}
+	injection.Openfda.ManufacturerName = []string{"Glucozen Pharma"}
+	injection.BoxedWarning = []string{"WARNING: RISK OF THYROID C-CELL TUMORS"}
+	tablet := testLabel("Glucozen", "20240115")
+	tablet.Openfda.Route = []string{"ORAL"}
+	tablet.Openfda.Rxcui = []string{"2200750"}
+	tablet.Openfda.ManufacturerName = []string{"Glucozen Oral"}
+	srv := newFDATestServer(t, map[string][]fdaLabelResult{
+		exactBrandSearch("Glucozen"): {tablet, injection},
+	})
+
+	products := ProductList{
+		{BrandName: "Glucozen", IngredientName: "glucozide", MedicineType: "GLP-1", AdminRoute: "Subcutaneous Injection"},
+		{BrandName: "Glucozen", IngredientName: "glucozide", MedicineType: "GLP-1", AdminRoute: "Oral Tablet"},
+		{BrandName: "Glucozen", IngredientName: "glucozide", MedicineType: "GLP-1", AdminRoute: "Oral Tablet",
+			RxCUIs: []string{"123"}},
+	}
+	if _, err := EnrichFromFDA(products, srv.options()); err != nil {
+		t.Fatalf("EnrichFromFDA() failed: %v", err)
+	}
+	if got := products[0].RxCUIs; !slices.Equal(got, injection.Openfda.Rxcui) {
+		t.Errorf("injection RxCUIs = %v, want the injection label's %v", got, injection.Openfda.Rxcui)
+	}
+	if products[0].BoxedWarning == "" || products[0].Manufacturer != "Glucozen Pharma" {
+		t.Errorf("injection didn't get the injection label's boxed warning and manufacturer: %+v", products[0])
+	}
+	if got := products[1].RxCUIs; !slices.Equal(got, tablet.Openfda.Rxcui) {
+		t.Errorf("tablet RxCUIs = %v, want the tablet label's %v", got, tablet.Openfda.Rxcui)
+	}
+	if products[1].BoxedWarning != "" || products[1].Manufacturer != "Glucozen Oral" {
+		t.Errorf("tablet got another formulation's boxed warning or manufacturer: %+v", products[1])
+	}
+	if got := products[2].RxCUIs; !slices.Equal(got, []string{"123"}) {
+		t.Errorf("RxCUIs set by hand were replaced with %v without OverwriteRxCUIs", got)
+	}
+}
+
+func TestFDALabelSearchURLQuoting(t *testing.T) {
+	tests := []struct {
+		brandName    string
+		quotePhrases bool
+		wantSearch   string
+	}{
+		{"NovoLog Mix", true, `"NovoLog Mix"`},
+		{"NovoLog Mix", false, "NovoLog Mix"},
+		{"Ozempic", true, "Ozempic"},
+	}
+	for _, tt := range tests {
+		u, err := url.Parse(fdaLabelSearchURL(tt.brandName, FDALookupOptions{QuotePhrases: tt.quotePhrases}))
+		if err != nil {
+			t.Fatalf("fdaLabelSearchURL(%q) isn't a URL: %v", tt.brandName, err)
+		}
+		if got := u.Query().Get("search"); got != tt.wantSearch {
+			t.Errorf("fdaLabelSearchURL(%q) with QuotePhrases %t searches %q, want %q", tt.brandName, tt.quotePhrases, got, tt.wantSearch)
+		}
+	}
+}
+
+func TestEnrichFromFDAVerifyNDC(t *testing.T) {
+	label := testLabel("Glucozen", "20250301")
+	label.Openfda.ProductNdc = []string{"0169-4130"}
+	label.Openfda.PackageNdc = []string{"0169-4130-13"}
+	srv := newFDATestServer(t, map[string][]fdaLabelResult{
+		exactBrandSearch("Glucozen"): {label},
+	})
+	opts := srv.options()
+	opts.VerifyNDC = true
+
+	matching := ProductList{{BrandName: "Glucozen", IngredientName: "glucozide", MedicineType: "GLP-1",
+		AdminRoute: "Subcutaneous Injection", NDCs: []string{"0169-4130", "0169-4130-13"}}}
+	if _, err := EnrichFromFDA(matching, opts); err != nil {
+		t.Errorf("EnrichFromFDA() with NDCs on the label = %v, want nil", err)
+	}
+
+	mismatching := ProductList{{BrandName: "Glucozen", IngredientName: "glucozide", MedicineType: "GLP-1",
+		AdminRoute: "Subcutaneous Injection", NDCs: []string{"0169-4130-13", "0169-9999-01"}}}
+	_, err := EnrichFromFDA(mismatching, opts)
+	if !errors.Is(err, ErrUnverifiedNDCs) || !strings.Contains(err.Error(), "0169-9999-01") || strings.Contains(err.Error(), "0169-4130-13") {
+		t.Errorf("EnrichFromFDA() with an NDC missing from the label = %v, want an error naming only 0169-9999-01", err)
+	}
+}
+
+func TestEnrichFromFDAGenericNameFallback(t *testing.T) {
+	injection := testLabel("Other Brand", "20250301")
+	injection.Openfda.GenericName = []string{"GLUCOZIDE"}
+	injection.Openfda.Route = []string{"SUBCUTANEOUS"}
+	// newer, but a pill's label
+	tablet := testLabel("Other Brand", "20250601")
+	tablet.Openfda.GenericName = []string{"GLUCOZIDE"}
+	tablet.Openfda.Route = []string{"ORAL"}
+	// the same route, but a different drug that mentions the ingredient
+	combination := testLabel("Combo Brand", "20250901")
+	combination.Openfda.GenericName = []string{"GLUCOZIDE AND SUGARBANIDE"}
+	combination.Openfda.Route = []string{"SUBCUTANEOUS"}
+	srv := newFDATestServer(t, map[string][]fdaLabelResult{
+		`openfda.generic_name:"Glucozide"`: {tablet, combination, injection},
+	})
+
+	products := ProductList{{BrandName: "Glucozen", IngredientName: "Glucozide", MedicineType: "GLP-1",
+		AdminRoute: "Subcutaneous Injection"}}
+	if _, err := EnrichFromFDA(products, srv.options()); err != nil {
+		t.Fatalf("EnrichFromFDA() failed: %v", err)
+	}
+	if products[0].FDALabelLatest != "2025-03-01" {
+		t.Errorf("FDALabelLatest = %q, want the injection's 2025-03-01 from the generic name search", products[0].FDALabelLatest)
+	}
+}
+
+func TestEnrichFromFDARecordedDate(t *testing.T) {
+	srv := newFDATestServer(t, map[string][]fdaLabelResult{
+		exactBrandSearch("Glucozen"): {testLabel("Glucozen", "20250301")},
+	})
+	tests := []struct {
+		recorded        string
+		wantNeedsUpdate bool
+	}{
+		{"2024-11-20", true},
+		{"2025-03-01", false},
+		{"2025-06-01", false},
+	}
+	for _, tt := range tests {
+		products := ProductList{{BrandName: "Glucozen", IngredientName: "glucozide", MedicineType: "GLP-1",
+			AdminRoute: "Subcutaneous Injection", FDALabelUpdated: tt.recorded}}
+		if _, err := EnrichFromFDA(products, srv.options()); err != nil {
+			t.Fatalf("EnrichFromFDA() with label recorded %s failed: %v", tt.recorded, err)
+		}
+		if products[0].FDALabelNeedsUpdate != tt.wantNeedsUpdate {
+			t.Errorf("label recorded %s against the FDA's 2025-03-01: FDALabelNeedsUpdate = %t, want %t",
+				tt.recorded, products[0].FDALabelNeedsUpdate, tt.wantNeedsUpdate)
+		}
+		if products[0].FDALabelLatest != "2025-03-01" || products[0].FDALabelRecencyNotFound {
+			t.Errorf("label recorded %s: FDALabelLatest = %q, not found %t, want 2025-03-01 found",
+				tt.recorded, products[0].FDALabelLatest, products[0].FDALabelRecencyNotFound)
+		}
+	}
+}
+
+func TestEnrichFromFDANoResults(t *testing.T) {
+	srv := newFDATestServer(t, nil)
+	products := ProductList{{BrandName: "Glucozen", IngredientName: "glucozide", MedicineType: "GLP-1",
+		AdminRoute: "Subcutaneous Injection", FDALabelUpdated: "2025-03-01"}}
+	if _, err := EnrichFromFDA(products, srv.options()); err != nil {
+		t.Fatalf("EnrichFromFDA() with no FDA results failed: %v", err)
+	}
+	if !products[0].FDALabelRecencyNotFound || products[0].FDALabelLatest != "" {
+		t.Errorf("product without FDA results: not found %t, FDALabelLatest %q, want not found and no date",
+			products[0].FDALabelRecencyNotFound, products[0].FDALabelLatest)
+	}
+	// exact brand name, brand name and generic name searches
+	if n := srv.requests.Load(); n != 3 {
+		t.Errorf("%d FDA requests made, want every search strategy tried once", n)
+	}
+}
+
+func TestEnrichFromFDASkipsOtherBrands(t *testing.T) {
+	// the loose brand name search matches other products that mention the brand
+	srv := newFDATestServer(t, map[string][]fdaLabelResult{
+		"Glucozen":  {testLabel("Glucozen XR", "20250901"), testLabel("GLUCOZEN", "20250301"), testLabel("Sugarbane", "20251001")},
+		"Sugarbane": {testLabel("Sugarbane Plus", "20250901")},
+	})
+	products := ProductList{
+		{BrandName: "Glucozen", MedicineType: "GLP-1", AdminRoute: "Subcutaneous Injection"},
+		{BrandName: "Sugarbane", MedicineType: "SGLT-2", AdminRoute: "Oral Tablet"},
+	}
+	if _, err := EnrichFromFDA(products, srv.options()); err != nil {
+		t.Fatalf("EnrichFromFDA() failed: %v", err)
+	}
+	if products[0].FDALabelLatest != "2025-03-01" {
+		t.Errorf("FDALabelLatest = %q, want 2025-03-01 from the label with the same brand name", products[0].FDALabelLatest)
+	}
+	if !products[1].FDALabelRecencyNotFound || products[1].FDALabelLatest != "" {
+		t.Errorf("product with only another brand's label: not found %t, FDALabelLatest %q, want not found",
+			products[1].FDALabelRecencyNotFound, products[1].FDALabelLatest)
+	}
+}
+
+func TestEnrichFromFDAEffectiveTime(t *testing.T) {
+	tests := []struct {
+		name           string
+		effectiveTimes []string
+		wantLatest     string
+		wantErr        bool
+	}{
+		{"newest wins", []string{"20240115", "20250301", "20231231"}, "2025-03-01", false},
+		{"malformed skipped", []string{"", "2025-06-01", "20240115", "202509"}, "2024-01-15", false},
+		{"only malformed", []string{"", "2025-06-01"}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var labels []fdaLabelResult
+			for _, e := range tt.effectiveTimes {
+				labels = append(labels, testLabel("Glucozen", e))
+			}
+			srv := newFDATestServer(t, map[string][]fdaLabelResult{exactBrandSearch("Glucozen"): labels})
+			products := ProductList{{BrandName: "Glucozen", MedicineType: "GLP-1", AdminRoute: "Subcutaneous Injection"}}
+			_, err := EnrichFromFDA(products, srv.options())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EnrichFromFDA() with effective times %q = %v, want error %t", tt.effectiveTimes, err, tt.wantErr)
+			}
+			if !tt.wantErr && products[0].FDALabelLatest != tt.wantLatest {
+				t.Errorf("FDALabelLatest = %q, want %s", products[0].FDALabelLatest, tt.wantLatest)
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package medcatalog
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// the date values are replaced in place with a regexp rather than re-encoding the file so key order,
+// indentation and (for YAML) comments are kept
+var (
+	jsonLabelUpdatedRe = regexp.MustCompile(`("fda_label_file_updated"\s*:\s*")[^"]*"`)
+	yamlLabelUpdatedRe = regexp.MustCompile(`(?m)^(fda_label_file_updated:[ \t]*["']?)\d{4}-\d{2}-\d{2}`)
+)
+
+// writeBackLabelDate sets fda_label_file_updated in the catalog file to date. the key has to already
+// be in the file, there's no reliable place to add it without re-encoding
+func writeBackLabelDate(sourceFile, date string) error {
+	path := sourceFile
+	info, err := os.Stat(path)
+	if err != nil {
+		return errors.Join(errors.New("failed reading file "+sourceFile), err)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return errors.Join(errors.New("failed reading file "+sourceFile), err)
+	}
+	re, replacement := jsonLabelUpdatedRe, "${1}"+date+`"`
+	if strings.ToLower(filepath.Ext(sourceFile)) != ".json" {
+		re, replacement = yamlLabelUpdatedRe, "${1}"+date
+	}
+	if !re.Match(content) {
+		return fmt.Errorf("%s has no fda_label_file_updated date to replace, add it by hand", sourceFile)
+	}
+	updated := re.ReplaceAll(content, []byte(replacement))
+	if err = os.WriteFile(path, updated, info.Mode().Perm()); err != nil {
+		return errors.Join(errors.New("failed writing file "+sourceFile), err)
+	}
+	return nil
+}
+
+// LabelDateUpdate is one fda_label_file_updated date WriteBackLabelUpdates changed, From is "none"
+// when the file had no date recorded
+type LabelDateUpdate struct {
+	File     string
+	From, To string
+}
+
+// WriteBackLabelUpdates records the FDA's effective date in the catalog file of every product whose
+// label is newer than the recorded one, and updates the products to match so this render agrees with
+// the next one. it returns the dates it changed
+func WriteBackLabelUpdates(list ProductList) ([]LabelDateUpdate, error) {
+	updates := []LabelDateUpdate{}
+	errs := []error{}
+	for i, p := range list {
+		if !p.FDALabelNeedsUpdate || p.FDALabelLatest == "" {
+			continue
+		}
+		if err := writeBackLabelDate(p.sourceFile, p.FDALabelLatest); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		recorded := p.FDALabelUpdated
+		if recorded == "" {
+			recorded = "none"
+		}
+		updates = append(updates, LabelDateUpdate{File: p.sourceFile, From: recorded, To: p.FDALabelLatest})
+		list[i].FDALabelUpdated = p.FDALabelLatest
+		list[i].FDALabelNeedsUpdate = false
+	}
+	return updates, errors.Join(errs...)
+}
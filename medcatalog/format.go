@@ -0,0 +1,68 @@
+package medcatalog
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// canonicalProductJSON is the formatted form of a catalog file: the product struct's key order,
+// two space indentation and a trailing newline. keys left at their zero value are dropped
+func canonicalProductJSON(content []byte) ([]byte, error) {
+	var p Product
+	dec := json.NewDecoder(bytes.NewReader(content))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&p); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, errors.New("unexpected data after the product object")
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false) // keep & and < readable in descriptions
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// FormatFiles rewrites every JSON catalog file in dir that isn't already canonical, returning the
+// ones that changed. with write false nothing is written, for checking in CI. YAML files are left
+// alone, re-encoding them would lose their comments
+func FormatFiles(dir string, write bool) ([]string, error) {
+	files, err := catalogFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	changed := []string{}
+	for _, file := range files {
+		if strings.ToLower(filepath.Ext(file)) != ".json" {
+			continue
+		}
+		path := catalogPath(dir, file)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return changed, errors.Join(errors.New("failed reading file "+file), err)
+		}
+		formatted, err := canonicalProductJSON(content)
+		if err != nil {
+			return changed, errors.Join(errors.New("failed formatting file "+file), err)
+		}
+		if bytes.Equal(content, formatted) {
+			continue
+		}
+		changed = append(changed, path)
+		if !write {
+			continue
+		}
+		if err = os.WriteFile(path, formatted, 0o644); err != nil {
+			return changed, errors.Join(errors.New("failed writing file "+file), err)
+		}
+	}
+	return changed, nil
+}
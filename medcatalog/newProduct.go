@@ -0,0 +1,96 @@
+package medcatalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// newProductTemplate is what -new-product writes. the free text values are TODOs and the values with
+// a fixed set of choices are left empty, both fail validation (not loading, so every one of them is
+// listed with its choices) until they're filled in so a half finished product can't be published
+type newProductTemplate struct {
+	IngredientName  string                      `json:"ingredient_name"`
+	BrandName       string                      `json:"brand_name"`
+	MedicineType    string                      `json:"medicine_type"`
+	AdminRoute      string                      `json:"administration_route"`
+	DoseFrequency   string                      `json:"dose_frequency"`
+	Savings         []newProductSavingsTemplate `json:"savings"`
+	FDALabelFile    string                      `json:"fda_label_file"`
+	FDALabelUpdated string                      `json:"fda_label_file_updated"`
+}
+
+type newProductSavingsTemplate struct {
+	Type        string `json:"type"`
+	Description string `json:"description"`
+	Phone       string `json:"phone"`
+	Link        string `json:"link"`
+	Eligibility struct {
+		PrivateInsurance    bool     `json:"private_insurance"`
+		GovernmentInsurance bool     `json:"government_insurance"`
+		CashPay             bool     `json:"cash_pay"`
+		OtherCriteria       []string `json:"other_criteria"`
+	} `json:"eligibility"`
+}
+
+const todoPrefix = "TODO: "
+
+// hasTODOs reports whether any free text field still has a placeholder from the template, the empty
+// enum fields fail validation on their own
+func (p Product) hasTODOs() bool {
+	fields := []string{p.IngredientName, p.BrandName}
+	for _, s := range p.Savings {
+		fields = append(fields, s.Description, s.Phone)
+		fields = append(fields, s.Eligibility.OtherCriteria...)
+	}
+	for _, f := range fields {
+		if strings.HasPrefix(f, todoPrefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func todo(hint string) string {
+	return todoPrefix + hint
+}
+
+// ScaffoldProduct writes a template <slug>.json in the catalog directory dir for a new brand,
+// refusing to overwrite an existing file, and returns the path it wrote
+func ScaffoldProduct(dir, brandName string) (string, error) {
+	brandName = strings.TrimSpace(brandName)
+	slug := Slugify(brandName)
+	if slug == "" {
+		return "", fmt.Errorf("brand name '%s' doesn't have any letters or numbers to name the file with", brandName)
+	}
+	path := catalogPath(dir, slug+".json")
+
+	savings := newProductSavingsTemplate{
+		Description: todo("what the program offers, e.g. pay as little as $25 a month"),
+		Phone:       todo("program phone number like 1-800-555-5555, or remove"),
+	}
+	savings.Eligibility.OtherCriteria = []string{todo("any other eligibility criteria, or remove")}
+	p := newProductTemplate{
+		IngredientName: todo("active ingredient"),
+		BrandName:      brandName,
+		Savings:        []newProductSavingsTemplate{savings},
+	}
+	content, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return "", errors.Join(errors.New("failed encoding new product"), err)
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if errors.Is(err, os.ErrExist) {
+		return "", fmt.Errorf("%s already exists, not overwriting it", path)
+	} else if err != nil {
+		return "", errors.Join(errors.New("failed creating "+path), err)
+	}
+	defer f.Close()
+	if _, err = f.Write(append(content, '\n')); err != nil {
+		return "", errors.Join(errors.New("failed writing "+path), err)
+	}
+	return path, nil
+}
@@ -0,0 +1,62 @@
+package medcatalog
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScaffoldProductFailsValidationNotLoading(t *testing.T) {
+	dir := t.TempDir()
+	schema, err := os.ReadFile("../" + DefaultDir + catalogSchemaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(dir, catalogSchemaFile), schema, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = ScaffoldProduct(dir, "Glucozen XR"); err != nil {
+		t.Fatalf("ScaffoldProduct() failed: %v", err)
+	}
+	if _, err = ScaffoldProduct(dir, "Glucozen XR"); err == nil {
+		t.Error("ScaffoldProduct() overwrote an existing file")
+	}
+
+	products, err := Load(dir)
+	if err != nil {
+		t.Fatalf("scaffolded product doesn't load: %v", err)
+	}
+	errs := Validate(products)
+	messages := []string{}
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	all := strings.Join(messages, "\n")
+	for _, want := range []string{"TODO placeholder", "Medicine type", "Dose frequency", "Administration route", "Copay Discount Card"} {
+		if !strings.Contains(all, want) {
+			t.Errorf("validating the scaffold doesn't mention %q:\n%s", want, all)
+		}
+	}
+}
+
+func TestFormatScaffoldProduct(t *testing.T) {
+	dir := t.TempDir()
+	path, err := ScaffoldProduct(dir, "Glucozen XR")
+	if err != nil {
+		t.Fatalf("ScaffoldProduct() failed: %v", err)
+	}
+	if _, err = FormatFiles(dir, false); err != nil {
+		t.Fatalf("checking the format of a scaffolded product failed: %v", err)
+	}
+	if _, err = FormatFiles(dir, true); err != nil {
+		t.Fatalf("formatting a scaffolded product failed: %v", err)
+	}
+	changed, err := FormatFiles(dir, false)
+	if err != nil || len(changed) != 0 {
+		t.Errorf("scaffolded product %s after formatting = %v, %v, want nothing left to format", path, changed, err)
+	}
+	if _, err = Load(dir); err != nil {
+		t.Errorf("formatted scaffold doesn't load: %v", err)
+	}
+}
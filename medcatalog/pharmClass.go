@@ -0,0 +1,108 @@
+package medcatalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"time"
+)
+
+// in the catalog directory, a pinned snapshot of the pharm_class_epc values OpenFDA knows about
+const PharmClassSnapshotFile = "pharmClasses.json"
+
+// medicineTypePharmClasses maps our medicine types to the FDA established pharmacologic classes
+// (pharm_class_epc) a matching label should have. types that aren't drugs have no entry.
+var medicineTypePharmClasses = map[string][]string{
+	"GLP-1":  {"GLP-1 Receptor Agonist [EPC]"},
+	"SGLT-2": {"Sodium-Glucose Cotransporter 2 Inhibitor [EPC]"},
+	"DPP-4":  {"Dipeptidyl Peptidase 4 Inhibitor [EPC]"},
+	"Insulin": {
+		"Insulin [EPC]",
+		"Insulin Analog [EPC]",
+	},
+}
+
+type fdaCountData struct {
+	Results []struct {
+		Term  string `json:"term"`
+		Count int    `json:"count"`
+	} `json:"results"`
+}
+
+// RefreshPharmClassSnapshot fetches every pharm_class_epc value from OpenFDA and pins them to the
+// snapshot file in the catalog directory dir, returning the classes it pinned
+func RefreshPharmClassSnapshot(dir string) ([]string, error) {
+	u, _ := url.Parse(fdaLabelAPIBase)
+	q := u.Query()
+	q.Set("count", "openfda.pharm_class_epc.exact")
+	q.Set("limit", "1000")
+	u.RawQuery = q.Encode()
+
+	c := http.Client{Timeout: 30 * time.Second}
+	req, _ := http.NewRequest("GET", u.String(), nil)
+	req.Header.Set("User-Agent", "pugnare.health/1.0")
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("error making FDA API request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("FDA API returned non-200 status (%d) url: %s", resp.StatusCode, u.String())
+	}
+	var counts fdaCountData
+	if err = json.NewDecoder(resp.Body).Decode(&counts); err != nil {
+		return nil, fmt.Errorf("failed to decode api json response: %w", err)
+	}
+	classes := []string{}
+	for _, r := range counts.Results {
+		classes = append(classes, r.Term)
+	}
+	slices.Sort(classes)
+
+	content, err := json.MarshalIndent(classes, "", "    ")
+	if err != nil {
+		return nil, errors.Join(errors.New("failed encoding pharm class snapshot"), err)
+	}
+	path := catalogPath(dir, PharmClassSnapshotFile)
+	if err = os.WriteFile(path, append(content, '\n'), 0o644); err != nil {
+		return nil, errors.Join(errors.New("failed writing "+path), err)
+	}
+	return classes, nil
+}
+
+// LoadPharmClassSnapshot returns the pharm classes pinned in the catalog directory dir, or nil if
+// there is no snapshot yet
+func LoadPharmClassSnapshot(dir string) ([]string, error) {
+	path := catalogPath(dir, PharmClassSnapshotFile)
+	content, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Join(errors.New("failed reading "+path), err)
+	}
+	var classes []string
+	if err = json.Unmarshal(content, &classes); err != nil {
+		return nil, errors.Join(errors.New("failed parsing JSON in "+path), err)
+	}
+	return classes, nil
+}
+
+// ValidatePharmClassMapping makes sure every class in medicineTypePharmClasses is a real FDA class
+func ValidatePharmClassMapping(snapshot []string) error {
+	for medicineType, classes := range medicineTypePharmClasses {
+		if err := MedTypeEnum.CheckError(medicineType); err != nil {
+			return fmt.Errorf("pharm class mapping has an unknown medicine type: %w", err)
+		}
+		for _, class := range classes {
+			if !slices.Contains(snapshot, class) {
+				return fmt.Errorf("pharm class '%s' mapped to medicine type '%s' isn't in %s",
+					class, medicineType, PharmClassSnapshotFile)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package medcatalog
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestValidatePharmClassMapping(t *testing.T) {
+	classes := []string{
+		"Dipeptidyl Peptidase 4 Inhibitor [EPC]",
+		"GLP-1 Receptor Agonist [EPC]",
+		"Insulin Analog [EPC]",
+		"Insulin [EPC]",
+		"Sodium-Glucose Cotransporter 2 Inhibitor [EPC]",
+	}
+	if err := ValidatePharmClassMapping(classes); err != nil {
+		t.Errorf("ValidatePharmClassMapping() with every mapped class = %v, want nil", err)
+	}
+	missing := slices.DeleteFunc(slices.Clone(classes), func(c string) bool { return c == "Insulin Analog [EPC]" })
+	if err := ValidatePharmClassMapping(missing); err == nil {
+		t.Error("ValidatePharmClassMapping() without a mapped class = nil, want an error")
+	}
+}
+
+func TestEnrichFromFDAPharmClassMismatch(t *testing.T) {
+	matching := testLabel("Glucozen", "20250301")
+	matching.Openfda.PharmClassEpc = []string{"GLP-1 Receptor Agonist [EPC]"}
+	mismatched := testLabel("Sugarbane", "20250301")
+	mismatched.Openfda.PharmClassEpc = []string{"Sulfonylurea [EPC]"}
+	srv := newFDATestServer(t, map[string][]fdaLabelResult{
+		exactBrandSearch("Glucozen"):  {matching},
+		exactBrandSearch("Sugarbane"): {mismatched},
+	})
+
+	products := ProductList{
+		{BrandName: "Glucozen", IngredientName: "glucozide", MedicineType: "GLP-1", AdminRoute: "Subcutaneous Injection"},
+		{BrandName: "Sugarbane", IngredientName: "sugarbanide", MedicineType: "SGLT-2", AdminRoute: "Oral Tablet"},
+	}
+	if _, err := EnrichFromFDA(products, srv.options()); err != nil {
+		t.Fatalf("EnrichFromFDA() failed: %v", err)
+	}
+	hasMismatch := func(p Product) bool {
+		return slices.ContainsFunc(p.Warnings(), func(w Warning) bool { return w.Rule == "pharm_class_mismatch" })
+	}
+	if hasMismatch(products[0]) {
+		t.Error("label with the medicine type's pharm class was reported as a mismatch")
+	}
+	if !hasMismatch(products[1]) {
+		t.Errorf("label without the medicine type's pharm class wasn't reported, warnings: %+v", products[1].Warnings())
+	}
+}
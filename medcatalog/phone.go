@@ -0,0 +1,94 @@
+package medcatalog
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// punctuation allowed between the digits of a phone number
+var phoneSeparatorsRe = regexp.MustCompile(`[\s().\-]+`)
+
+// countryCallingCodes are the countries a savings program's country hint can name, with the code
+// dialed before their national numbers. the US and Canada share the North American Numbering Plan
+var countryCallingCodes = map[string]string{
+	"US": "1",
+	"CA": "1",
+	"MX": "52",
+	"GB": "44",
+	"IE": "353",
+	"AU": "61",
+	"NZ": "64",
+}
+
+// E.164 allows at most 15 digits including the country code, anything under 8 is too short to dial
+const (
+	e164MaxDigits = 15
+	e164MinDigits = 8
+)
+
+// normalizePhone turns a phone number into the form it's stored and rendered in, reporting false if
+// it can't be parsed. North American numbers (1-800-555-5555, (800) 555-5555, 800.555.5555,
+// +1 800 555 5555, 8005555555) become 1-800-555-5555. other international numbers become E.164
+// (+442079460000), either written with a leading + or as a national number with the program's
+// country hint (020 7946 0000 with country GB). no hint means the US.
+func normalizePhone(phone, country string) (string, bool) {
+	digits := phoneSeparatorsRe.ReplaceAllString(strings.TrimSpace(phone), "")
+	international := strings.HasPrefix(digits, "+")
+	digits = strings.TrimPrefix(digits, "+")
+	if digits == "" || strings.Trim(digits, "0123456789") != "" {
+		return phone, false
+	}
+
+	callingCode := "1"
+	if country != "" {
+		var ok bool
+		if callingCode, ok = countryCallingCodes[country]; !ok {
+			return phone, false
+		}
+	}
+	// the country hint, if there is one, has to agree with an international number's country code
+	if international && country != "" && !strings.HasPrefix(digits, callingCode) {
+		return phone, false
+	}
+	switch {
+	case international && strings.HasPrefix(digits, "1"):
+		return normalizeNANPPhone(phone, digits)
+	case international:
+		return normalizeE164Phone(phone, digits)
+	case callingCode == "1":
+		return normalizeNANPPhone(phone, digits)
+	default:
+		// national numbers drop the trunk prefix 0 once the country code is in front
+		return normalizeE164Phone(phone, callingCode+strings.TrimPrefix(digits, "0"))
+	}
+}
+
+// normalizeNANPPhone formats a North American number as 1-800-555-5555
+func normalizeNANPPhone(phone, digits string) (string, bool) {
+	if len(digits) == 10 {
+		digits = "1" + digits
+	}
+	if len(digits) != 11 || digits[0] != '1' {
+		return phone, false
+	}
+	return digits[:1] + "-" + digits[1:4] + "-" + digits[4:7] + "-" + digits[7:], true
+}
+
+// normalizeE164Phone formats the digits (country code first) as +<digits>
+func normalizeE164Phone(phone, digits string) (string, bool) {
+	if len(digits) < e164MinDigits || len(digits) > e164MaxDigits || digits[0] == '0' {
+		return phone, false
+	}
+	return "+" + digits, true
+}
+
+// phoneCountries lists the country hints that are understood, for error messages
+func phoneCountries() []string {
+	countries := []string{}
+	for country := range countryCallingCodes {
+		countries = append(countries, country)
+	}
+	slices.Sort(countries)
+	return countries
+}
@@ -0,0 +1,36 @@
+package medcatalog
+
+import "testing"
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		phone   string
+		country string
+		want    string
+		valid   bool
+	}{
+		{"1-800-555-5555", "", "1-800-555-5555", true},
+		{"(800) 555-5555", "", "1-800-555-5555", true},
+		{"800.555.5555", "", "1-800-555-5555", true},
+		{"8005555555", "", "1-800-555-5555", true},
+		{" 1 (800) 555 5555 ", "", "1-800-555-5555", true},
+		{"555-5555", "", "555-5555", false},
+		{"2-800-555-5555", "", "2-800-555-5555", false},
+		{"1-800-CALL-NOW", "", "1-800-CALL-NOW", false},
+		{"", "", "", false},
+		{"+1 800 555 5555", "", "1-800-555-5555", true},
+		{"+1 800 555 5555", "CA", "1-800-555-5555", true},
+		{"+1 800 555 5555", "GB", "+1 800 555 5555", false},
+		{"+44 20 7946 0000", "", "+442079460000", true},
+		{"+44 20 7946 0000", "GB", "+442079460000", true},
+		{"+44 20 7946 0000", "US", "+44 20 7946 0000", false},
+		{"020 7946 0000", "GB", "+442079460000", true},
+		{"800 555 5555", "XX", "800 555 5555", false},
+	}
+	for _, tt := range tests {
+		got, ok := normalizePhone(tt.phone, tt.country)
+		if got != tt.want || ok != tt.valid {
+			t.Errorf("normalizePhone(%q, %q) = %q, %t, want %q, %t", tt.phone, tt.country, got, ok, tt.want, tt.valid)
+		}
+	}
+}
@@ -0,0 +1,531 @@
+package medcatalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const fdaLabelHost = "www.accessdata.fda.gov"
+const fdaLabelPathPrefix = "/drugsatfda_docs/label/"
+
+func validateFDALabelLink(p Product) error {
+	// make sure the updated date is in YYYY-MM-DD format
+	updateTime, err := time.Parse("2006-01-02", p.FDALabelUpdated)
+	if err != nil {
+		return fmt.Errorf("Failed: FDA label updated date '%s' for product '%s' is not in YYYY-MM-DD format: %w", p.FDALabelUpdated, p.BrandName, err)
+	}
+	// it's impossible to have updated the label in the future
+	if updateTime.After(time.Now()) {
+		return fmt.Errorf("Failed: FDA label updated date '%s' for product '%s' is in the future", p.FDALabelUpdated, p.BrandName)
+	}
+	// make sure the link is valid (parses as a URL)
+	u, err := url.ParseRequestURI(p.FDALabelFile)
+	if err != nil {
+		return fmt.Errorf("Failed: FDA label file link '%s' for product '%s' is not a valid URL", p.FDALabelFile, p.BrandName)
+	}
+	// make sure the link is to FDA's label repository, scheme and host are case-insensitive (and a
+	// fully qualified host can end in a dot) so normalize them, but the host has to match exactly so
+	// look-alike hosts are rejected. the path is cleaned first so ../ can't climb out of the label
+	// directory, and credentials in the link are never legitimate
+	host := strings.TrimSuffix(strings.ToLower(u.Host), ".")
+	if strings.ToLower(u.Scheme) != "https" || host != fdaLabelHost || u.User != nil ||
+		!strings.HasPrefix(path.Clean(u.Path), fdaLabelPathPrefix) {
+		return fmt.Errorf("Failed: FDA label file link '%s' for product '%s' is not a valid FDA label repository URL", p.FDALabelFile, p.BrandName)
+	}
+	// it has to be a link to a PDF
+	if !strings.HasSuffix(strings.ToLower(u.Path), ".pdf") {
+		return fmt.Errorf("Failed: FDA label file link '%s' for product '%s' is not a link to a PDF file", p.FDALabelFile, p.BrandName)
+	}
+	// reachability is checked separately with -check-links since it needs the network
+	return nil
+}
+
+type Product struct {
+	IngredientName          string        `json:"ingredient_name"`
+	BrandName               string        `json:"brand_name"`
+	MedicineType            medicineType  `json:"medicine_type"`
+	AdminRoute              adminRoute    `json:"administration_route"`
+	DoseFrequency           doseFrequency `json:"dose_frequency,omitempty"`
+	Savings                 []SavingsInfo `json:"savings"`
+	SkipFDALabel            bool          `json:"skip_fda_label,omitempty"`
+	FDALabelFile            string        `json:"fda_label_file,omitempty"`
+	FDALabelLocalFile       string        `json:"fda_label_local_file,omitempty"`   // copy of the label under the site root, set by -download-labels
+	FDALabelUpdated         string        `json:"fda_label_file_updated,omitempty"` // YYYY-MM-DD
+	FDALabelNeedsUpdate     bool          `json:"fda_label_needs_update,omitempty"`
+	FDALabelLatest          string        `json:"fda_label_latest,omitempty"`       // YYYY-MM-DD effective date of the newest label found, set by the FDA check
+	FDALabelRecencyNotFound bool          `json:"fda_label_not_found,omitempty"`    // if we couldn't find a matching label in the FDA lookup
+	BoxedWarning            string        `json:"boxed_warning,omitempty"`          // from the newest matching FDA label, set by the FDA check
+	Manufacturer            string        `json:"manufacturer,omitempty"`           // from the newest matching FDA label, set by the FDA check
+	FDAApplicationNumber    string        `json:"fda_application_number,omitempty"` // NDA/BLA/ANDA number of the newest matching FDA label, set by the FDA check
+	ColorClass              string        `json:"color_class,omitempty"`            // derived from the medicine type, see medicineTypeColors
+	ListPosition            int           `json:"list_position,omitempty"`
+	Slug                    string        `json:"slug,omitempty"`        // defaults to a url safe form of the brand name
+	RxCUIs                  []string      `json:"rxcuis,omitempty"`      // RxNorm concept ids, filled from the FDA label when not set
+	NDCs                    []string      `json:"ndcs,omitempty"`        // National Drug Codes, product (e.g. 0169-4130) or package (e.g. 0169-4130-13)
+	ProductNDC              string        `json:"product_ndc,omitempty"` // looks the FDA label up by this product NDC instead of the brand name
+	Affordable              bool          `json:"affordable,omitempty"`  // derived, see isAffordable
+	sourceFile              string        // catalog file the product was loaded from, unexported so it's never serialized
+	nameFixes               []string      // names Load had to trim or collapse the whitespace of, see normalizeNames
+	fdaWarnings             []Warning     // findings from the FDA check, see EnrichFromFDA
+	settings                *Settings     // the enums and config Load read the product with, see Settings
+}
+
+var rxcuiRe = regexp.MustCompile(`^\d+$`)
+
+// labeler-product or labeler-product-package, 10 digits in a package NDC (4-4-2, 5-3-2 or 5-4-1)
+var ndcRe = regexp.MustCompile(`^(\d{4}-\d{4}|\d{5}-\d{3}|\d{5}-\d{4})(-\d{1,2})?$`)
+
+// just labeler-product, which is what OpenFDA indexes as openfda.product_ndc
+var productNDCRe = regexp.MustCompile(`^(\d{4}-\d{4}|\d{5}-\d{3}|\d{5}-\d{4})$`)
+
+// SourceFile is the catalog file the product was loaded from
+func (p Product) SourceFile() string {
+	return p.sourceFile
+}
+
+// normalizeNames trims the brand and ingredient names and collapses runs of whitespace inside them,
+// so " Ozempic" and "Ozempic" are the same product everywhere names are compared or used as keys
+func (p *Product) normalizeNames() {
+	for _, name := range []struct {
+		field string
+		value *string
+	}{{"brand_name", &p.BrandName}, {"ingredient_name", &p.IngredientName}} {
+		normalized := strings.Join(strings.Fields(*name.value), " ")
+		if normalized != *name.value {
+			p.nameFixes = append(p.nameFixes, fmt.Sprintf("%s '%s' has extra whitespace, it's read as '%s', fix it in the catalog file",
+				name.field, *name.value, normalized))
+			*name.value = normalized
+		}
+	}
+}
+
+// IsDevice reports whether the product's medicine type is a device, which is exempt from FDA label checks
+func (p Product) IsDevice() bool {
+	return p.Settings().deviceMedicineTypes.Valid(string(p.MedicineType))
+}
+
+// SkipFDALookup reports whether the product has no FDA label to look up, either because it's a
+// device or because skip_fda_label is set
+func (p Product) SkipFDALookup() bool {
+	return p.SkipFDALabel || p.IsDevice()
+}
+
+// Validate returns every problem with the product joined into one error, or nil
+func (p Product) Validate() error {
+	return errors.Join(p.validationErrors()...)
+}
+
+func (p Product) validationErrors() []error {
+	errs := []error{}
+	for _, check := range productChecks {
+		errs = append(errs, check.check(p)...)
+	}
+	return errs
+}
+
+// productChecks are the per-product validation checks, each is its own rule in DefaultRules so
+// errors_by_rule in the run summary says which one failed
+var productChecks = []struct {
+	name  string
+	check func(Product) []error
+}{
+	{"required_fields", Product.requiredFieldErrors},
+	{"color_class", Product.colorClassErrors},
+	{"enum_values", Product.enumValueErrors},
+	{"savings_program", Product.savingsProgramErrors},
+	{"identifiers", Product.identifierErrors},
+	{"device_fields", Product.deviceFieldErrors},
+	{"fda_label_link", Product.fdaLabelLinkErrors},
+}
+
+// requiredFieldErrors checks that the unconstrained fields are filled in and aren't -new-product's
+// TODO placeholders
+func (p Product) requiredFieldErrors() []error {
+	errs := []error{}
+	if slices.Contains([]string{p.BrandName, p.IngredientName}, "") {
+		errs = append(errs, fmt.Errorf("Failed: Brand name '%s' and ingredient name '%s' cannot be empty for product '%s'",
+			p.BrandName, p.IngredientName, p.BrandName))
+	}
+	if len(p.Savings) == 0 {
+		errs = append(errs, fmt.Errorf("Failed: Savings information is empty for product '%s'", p.BrandName))
+	}
+	if p.hasTODOs() {
+		errs = append(errs, fmt.Errorf("Failed: product '%s' still has TODO placeholder values from -new-product", p.BrandName))
+	}
+	return errs
+}
+
+func (p Product) colorClassErrors() []error {
+	if p.ColorClass != "" && !validColorClass(p.ColorClass) {
+		return []error{fmt.Errorf("Failed: color class '%s' for product '%s' isn't one of the colors defined in medcatalog/colors.go",
+			p.ColorClass, p.BrandName)}
+	}
+	return nil
+}
+
+// enumValueErrors checks the medicine type, dose frequency and administration route are in their lists,
+// devices without a dose frequency use N/A
+func (p Product) enumValueErrors() []error {
+	errs := []error{}
+	s := p.Settings()
+	if err := s.medicineTypes.CheckError(string(p.MedicineType)); err != nil {
+		errs = append(errs, fmt.Errorf("Failed: Medicine type for product '%s' is invalid: %w", p.BrandName, err))
+	}
+	if err := s.doseFrequencies.CheckError(string(p.DoseFrequency)); err != nil {
+		errs = append(errs, fmt.Errorf("Failed: Dose frequency for product '%s' is invalid: %w", p.BrandName, err))
+	}
+	if err := s.adminRoutes.CheckError(string(p.AdminRoute)); err != nil {
+		errs = append(errs, fmt.Errorf("Failed: Administration route for product '%s' is invalid: %w", p.BrandName, err))
+	}
+	return errs
+}
+
+// savingsProgramErrors validates each savings program's type, phone, link and dates
+func (p Product) savingsProgramErrors() []error {
+	errs := []error{}
+	for _, s := range p.Savings {
+		for _, err := range s.validationErrors(p.Settings().savingsTypes) {
+			errs = append(errs, fmt.Errorf("Failed: Savings program '%s' for product '%s' is invalid: %v", s.Description, p.BrandName, err))
+		}
+	}
+	return errs
+}
+
+// identifierErrors checks the format of the RxCUIs and NDCs
+func (p Product) identifierErrors() []error {
+	errs := []error{}
+	for _, rxcui := range p.RxCUIs {
+		if !rxcuiRe.MatchString(rxcui) {
+			errs = append(errs, fmt.Errorf("Failed: RxCUI '%s' for product '%s' is not a numeric string", rxcui, p.BrandName))
+		}
+	}
+	for _, ndc := range p.NDCs {
+		if !ndcRe.MatchString(ndc) {
+			errs = append(errs, fmt.Errorf("Failed: NDC '%s' for product '%s' is not in a dashed NDC format like 0169-4130-13", ndc, p.BrandName))
+		}
+	}
+	if p.ProductNDC != "" && !productNDCRe.MatchString(p.ProductNDC) {
+		errs = append(errs, fmt.Errorf("Failed: product NDC '%s' for product '%s' is not a dashed labeler-product code like 0169-4130", p.ProductNDC, p.BrandName))
+	}
+	return errs
+}
+
+// deviceFieldErrors checks devices and drugs don't have each other's fields, devices don't have FDA
+// drug labels so a label link on one is a mistake
+func (p Product) deviceFieldErrors() []error {
+	errs := []error{}
+	if p.IsDevice() && strings.TrimSpace(p.FDALabelFile) != "" {
+		errs = append(errs, fmt.Errorf("Failed: product '%s' has device medicine type '%s' and can't have an FDA label file",
+			p.BrandName, p.MedicineType))
+	}
+	if p.Settings().deviceRoutes.Valid(string(p.AdminRoute)) && !p.IsDevice() {
+		errs = append(errs, fmt.Errorf("Failed: product '%s' has device administration route '%s' but medicine type '%s' isn't a device",
+			p.BrandName, p.AdminRoute, p.MedicineType))
+	}
+	return errs
+}
+
+// fdaLabelLinkErrors validates the FDA label link if there is one
+func (p Product) fdaLabelLinkErrors() []error {
+	if strings.TrimSpace(p.FDALabelFile) == "" {
+		return nil
+	}
+	if err := validateFDALabelLink(p); err != nil {
+		return []error{fmt.Errorf("Failed: FDA label validation for product '%s': %v", p.BrandName, err)}
+	}
+	return nil
+}
+
+// ValidationError is a validation problem tied to the catalog file it came from, Rule groups them
+// for reporting
+type ValidationError struct {
+	File string
+	Rule string
+	Err  error
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%s: %v", e.File, e.Err)
+}
+
+func (e ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Validate checks every product, and the catalog as a whole, against DefaultRules returning all
+// the problems found rather than stopping at the first one
+func Validate(products []Product) []error {
+	return NewValidator(DefaultRules()...).Run(context.Background(), products)
+}
+
+// maxDescriptionLength is how many characters a savings description can have before it's warned
+// about as too long to read on a card by default, config.json can change it
+var maxDescriptionLength = 280
+
+// maxIncomeLimitFPLPercent is the highest believable income limit, no program goes past 10x the
+// federal poverty level
+const maxIncomeLimitFPLPercent = 1000
+
+// savingsExpiredMaxYears is how long ago an expires date can be before it's a validation error
+const savingsExpiredMaxYears = 2
+
+// SavingsInfo is one of a product's savings programs, e.g. a copay card or patient assistance program
+type SavingsInfo struct {
+	Type        savingsType `json:"type"`
+	Description string      `json:"description"`
+	Phone       string      `json:"phone,omitempty"`
+	Country     string      `json:"country,omitempty"` // ISO 3166 code (e.g. CA, GB) the phone number is dialed in, defaults to US
+	Link        string      `json:"link,omitempty"`
+	CopayCap    float64     `json:"copay_cap,omitempty"` // most a patient pays per month with the program, in dollars
+	Expires     string      `json:"expires,omitempty"`   // YYYY-MM-DD, the last day the offer can be used
+	Priority    int         `json:"priority,omitempty"`  // lower is shown first, unset programs go after the rest, see Decorate
+	Expired     bool        `json:"expired,omitempty"`   // derived, see Decorate
+	Eligibility struct {
+		PrivateInsurance    bool     `json:"private_insurance,omitempty"`
+		GovernmentInsurance bool     `json:"government_insurance,omitempty"`
+		CashPay             bool     `json:"cash_pay,omitempty"`
+		OtherCriteria       []string `json:"other_criteria,omitempty"`
+		// IncomeLimitFPLPercent is the most household income can be, as a percent of the federal
+		// poverty level (400 for 400% FPL), 0 when the program has no income limit
+		IncomeLimitFPLPercent int `json:"income_limit_fpl_percent,omitempty"`
+	} `json:"eligibility,omitempty"`
+}
+
+// Validate returns every problem with the savings program joined into one error, or nil. the type is
+// checked against the built-in savings types, a loaded product's are checked by Product.Validate
+func (s SavingsInfo) Validate() error {
+	return errors.Join(s.validationErrors(savingsTypeEnum)...)
+}
+
+func (s SavingsInfo) validationErrors(savingsTypes enum) []error {
+	errs := []error{}
+	if strings.TrimSpace(s.Description) == "" {
+		errs = append(errs, errors.New("Savings description cannot be empty for product"))
+	}
+	if s.Country != "" {
+		if _, ok := countryCallingCodes[s.Country]; !ok {
+			errs = append(errs, fmt.Errorf("Country '%s' for '%s' isn't one of %s", s.Country, s.Description, strings.Join(phoneCountries(), ", ")))
+		}
+	}
+	if strings.TrimSpace(s.Phone) != "" {
+		if normalized, ok := normalizePhone(s.Phone, s.Country); !ok || normalized != s.Phone {
+			errs = append(errs, fmt.Errorf("Phone number '%s' is not a US number like 1-800-555-5555 or (800) 555-5555, "+
+				"or an international one like +44 20 7946 0000 (set country for national formats)", s.Phone))
+		}
+	}
+	if strings.TrimSpace(s.Link) != "" {
+		if !strings.HasPrefix(s.Link, "http://") && !strings.HasPrefix(s.Link, "https://") {
+			errs = append(errs, fmt.Errorf("Link '%s' for product '%s' is not a valid URL (must start with http:// or https://)", s.Link, s.Description))
+		}
+	}
+	if s.CopayCap < 0 {
+		errs = append(errs, fmt.Errorf("Copay cap %.2f for '%s' can't be negative", s.CopayCap, s.Description))
+	}
+	if limit := s.Eligibility.IncomeLimitFPLPercent; limit < 0 || limit > maxIncomeLimitFPLPercent {
+		errs = append(errs, fmt.Errorf("Income limit %d%% FPL for '%s' isn't a percent of the federal poverty level between 0 and %d",
+			limit, s.Description, maxIncomeLimitFPLPercent))
+	}
+	if s.Priority < 0 {
+		errs = append(errs, fmt.Errorf("Priority %d for '%s' can't be negative, 1 is shown first", s.Priority, s.Description))
+	}
+	if s.Expires != "" {
+		// an offer that ended years ago is stale data to delete, not one to mark as expired
+		if expires, err := time.Parse("2006-01-02", s.Expires); err != nil {
+			errs = append(errs, fmt.Errorf("Expires '%s' for '%s' is not in YYYY-MM-DD format", s.Expires, s.Description))
+		} else if expires.Before(time.Now().AddDate(-savingsExpiredMaxYears, 0, 0)) {
+			errs = append(errs, fmt.Errorf("'%s' expired on %s, more than %d years ago, remove it", s.Description, s.Expires, savingsExpiredMaxYears))
+		}
+	}
+	if err := savingsTypes.CheckError(string(s.Type)); err != nil {
+		errs = append(errs, fmt.Errorf("Invalid savings type for '%s': %w", s.Description, err))
+	}
+	// a program nobody qualifies for is an unfinished entry, not an offer
+	if !s.hasEligibilityPath() {
+		errs = append(errs, fmt.Errorf("%s has no eligibility path, private_insurance, government_insurance and cash_pay are all false and there are no other_criteria", s.Type))
+	}
+
+	return errs
+}
+
+// hasEligibilityPath is whether any eligibility flag is set or there's at least one non-blank other criterion
+func (s SavingsInfo) hasEligibilityPath() bool {
+	e := s.Eligibility
+	if e.PrivateInsurance || e.GovernmentInsurance || e.CashPay {
+		return true
+	}
+	return slices.ContainsFunc(e.OtherCriteria, func(c string) bool { return strings.TrimSpace(c) != "" })
+}
+
+// eligibilityPhrases maps phrases that can show up in other_criteria to the eligibility boolean they
+// imply. The list is intentionally short and only holds phrases that are unambiguous, matching is
+// case-insensitive on a substring of the criterion.
+var eligibilityPhrases = []struct {
+	phrase   string
+	field    string
+	expected bool
+}{
+	{"cash-paying patients only", "cash_pay", true},
+	{"cash pay only", "cash_pay", true},
+	{"uninsured patients only", "cash_pay", true},
+	{"not valid for cash", "cash_pay", false},
+	{"not valid with government insurance", "government_insurance", false},
+	{"not valid with medicare", "government_insurance", false},
+	{"not valid with medicaid", "government_insurance", false},
+	{"medicare patients only", "government_insurance", true},
+	{"commercial insurance only", "private_insurance", true},
+	{"commercially insured patients only", "private_insurance", true},
+	{"private insurance only", "private_insurance", true},
+	{"not valid with commercial insurance", "private_insurance", false},
+	{"not valid with private insurance", "private_insurance", false},
+}
+
+// weeklyInjectableClasses are medicine types where approved injectables are almost all dosed weekly,
+// so a daily injectable in one is more likely a data mistake than not
+var weeklyInjectableClasses = []string{"GLP-1"}
+
+// weeklyInjectableExceptions are brand names known to be non-weekly injectables in a weekly class,
+// a catalog's config.json can add more
+var weeklyInjectableExceptions = []string{"Victoza"}
+
+// Warning is a non-fatal finding, Rule groups them for reporting
+type Warning struct {
+	Rule    string
+	Message string
+}
+
+// Warnings returns the non-fatal findings for the product and its savings programs
+func (p Product) Warnings() []Warning {
+	warnings := []Warning{}
+	settings := p.Settings()
+	if slices.Contains(weeklyInjectableClasses, string(p.MedicineType)) && p.AdminRoute == "Subcutaneous Injection" &&
+		!strings.Contains(strings.ToLower(string(p.DoseFrequency)), "weekly") &&
+		!slices.Contains(settings.weeklyInjectableExceptions, p.BrandName) {
+		warnings = append(warnings, Warning{"weekly_injectable", fmt.Sprintf(
+			"%s injectables are usually weekly but dose frequency is '%s', please verify (or add an exception to %s)",
+			p.MedicineType, p.DoseFrequency, ConfigFile)})
+	}
+	// drugs are expected to link their label, devices don't have one and skip_fda_label opts out
+	if !p.SkipFDALookup() && strings.TrimSpace(p.FDALabelFile) == "" {
+		warnings = append(warnings, Warning{"missing_fda_label",
+			"no fda_label_file, add a link to the current FDA label (or set skip_fda_label)"})
+	}
+	if derived := p.derivedColorClass(); p.ColorClass != "" && p.ColorClass != derived {
+		warnings = append(warnings, Warning{"color_class_mismatch", fmt.Sprintf(
+			"color class '%s' doesn't match '%s' for medicine type '%s', the medicine type's color is used, remove color_class",
+			p.ColorClass, derived, p.MedicineType)})
+	}
+	for _, fix := range p.nameFixes {
+		warnings = append(warnings, Warning{"name_whitespace", fix})
+	}
+	warnings = append(warnings, p.fdaWarnings...)
+	for _, s := range p.Savings {
+		if s.expired(time.Now()) {
+			warnings = append(warnings, Warning{"savings_expired", fmt.Sprintf(
+				"savings program '%s' expired on %s, update or remove it", s.Description, s.Expires)})
+		}
+		if n := utf8.RuneCountInString(s.Description); n > settings.maxDescriptionLength {
+			warnings = append(warnings, Warning{"long_description", fmt.Sprintf(
+				"savings program '%s' description is %d characters, over %d, shorten it (details belong on the program's site)",
+				s.Description, n, settings.maxDescriptionLength)})
+		}
+		if w := s.linkHostWarning(settings.savingsLinkDomains); w != "" {
+			warnings = append(warnings, Warning{"savings_link_host", w})
+		}
+		for _, w := range s.Warnings() {
+			warnings = append(warnings, Warning{"eligibility_contradiction",
+				fmt.Sprintf("savings program '%s': %s", s.Description, w)})
+		}
+	}
+	return warnings
+}
+
+// expired reports whether the offer's last day is before now's date, programs without an expires
+// date (or an unreadable one, which validation reports) never expire
+func (s SavingsInfo) expired(now time.Time) bool {
+	if s.Expires == "" {
+		return false
+	}
+	expires, err := time.ParseInLocation("2006-01-02", s.Expires, now.Location())
+	if err != nil {
+		return false
+	}
+	return now.After(expires.AddDate(0, 0, 1))
+}
+
+// Warnings returns non-fatal findings for the savings program, like other_criteria that contradict
+// the eligibility booleans.
+func (s SavingsInfo) Warnings() []string {
+	eligibility := map[string]bool{
+		"private_insurance":    s.Eligibility.PrivateInsurance,
+		"government_insurance": s.Eligibility.GovernmentInsurance,
+		"cash_pay":             s.Eligibility.CashPay,
+	}
+	warnings := []string{}
+	for _, criterion := range s.Eligibility.OtherCriteria {
+		lowerCriterion := strings.ToLower(criterion)
+		for _, ep := range eligibilityPhrases {
+			if strings.Contains(lowerCriterion, ep.phrase) && eligibility[ep.field] != ep.expected {
+				warnings = append(warnings, fmt.Sprintf("criterion '%s' implies %s is %t but it is %t",
+					criterion, ep.field, ep.expected, eligibility[ep.field]))
+			}
+		}
+	}
+	return warnings
+}
+
+// SortKeys are the -sort-by orders, each one falls back to brand name and then slug so the
+// order never depends on how the catalog was read
+var SortKeys = map[string]func(a, b Product) int{
+	"brand": func(a, b Product) int { return 0 },
+	"ingredient": func(a, b Product) int {
+		return strings.Compare(strings.ToLower(a.IngredientName), strings.ToLower(b.IngredientName))
+	},
+	"medicine-type": func(a, b Product) int {
+		return strings.Compare(string(a.MedicineType), string(b.MedicineType))
+	},
+	// newest label first, products without one last (YYYY-MM-DD sorts as text)
+	"fda-updated": func(a, b Product) int {
+		switch {
+		case a.FDALabelUpdated == "" && b.FDALabelUpdated != "":
+			return 1
+		case a.FDALabelUpdated != "" && b.FDALabelUpdated == "":
+			return -1
+		}
+		return strings.Compare(b.FDALabelUpdated, a.FDALabelUpdated)
+	},
+}
+
+const DefaultSortKey = "brand"
+
+// Sort sorts the products by ListPosition, products with ListPosition 0 (not set) go to the
+// end. products with the same ListPosition, and all the ones without, are ordered by sortBy
+func Sort(products []Product, sortBy string) []Product {
+	byKey := SortKeys[sortBy]
+	sorted := slices.Clone(products)
+	slices.SortStableFunc(sorted, func(a, b Product) int {
+		switch {
+		case a.ListPosition > 0 && b.ListPosition == 0:
+			return -1
+		case a.ListPosition == 0 && b.ListPosition > 0:
+			return 1
+		case a.ListPosition != b.ListPosition:
+			return a.ListPosition - b.ListPosition
+		}
+		if c := byKey(a, b); c != 0 {
+			return c
+		}
+		if c := strings.Compare(strings.ToLower(a.BrandName), strings.ToLower(b.BrandName)); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Slug, b.Slug)
+	})
+	return sorted
+}
@@ -0,0 +1,124 @@
+package medcatalog
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestValidateFDALabelLink(t *testing.T) {
+	tests := []struct {
+		name  string
+		link  string
+		valid bool
+	}{
+		{"canonical", "https://www.accessdata.fda.gov/drugsatfda_docs/label/2025/209637s025lbl.pdf", true},
+		{"uppercase scheme and host", "HTTPS://WWW.ACCESSDATA.FDA.GOV/drugsatfda_docs/label/2025/209637s025lbl.pdf", true},
+		{"fully qualified host", "https://www.accessdata.fda.gov./drugsatfda_docs/label/2025/209637s025lbl.pdf", true},
+		{"http", "http://www.accessdata.fda.gov/drugsatfda_docs/label/2025/209637s025lbl.pdf", false},
+		{"look-alike host suffix", "https://www.accessdata.fda.gov.evil.example/drugsatfda_docs/label/2025/x.pdf", false},
+		{"extra subdomain", "https://labels.www.accessdata.fda.gov/drugsatfda_docs/label/2025/x.pdf", false},
+		{"credentials", "https://www.accessdata.fda.gov@evil.example/drugsatfda_docs/label/2025/x.pdf", false},
+		{"path climbing out", "https://www.accessdata.fda.gov/drugsatfda_docs/label/../../scripts/x.pdf", false},
+		{"not a pdf", "https://www.accessdata.fda.gov/drugsatfda_docs/label/2025/209637s025lbl.html", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Product{BrandName: "Glucozen", FDALabelFile: tt.link, FDALabelUpdated: "2025-01-15"}
+			if err := validateFDALabelLink(p); (err == nil) != tt.valid {
+				t.Errorf("validateFDALabelLink(%s) = %v, want valid %t", tt.link, err, tt.valid)
+			}
+		})
+	}
+}
+
+// testSavings is a valid savings program open to privately insured patients
+func testSavings(description string) SavingsInfo {
+	s := SavingsInfo{Type: "Copay Discount Card", Description: description}
+	s.Eligibility.PrivateInsurance = true
+	return s
+}
+
+func TestSavingsExpiredWarning(t *testing.T) {
+	expired, current := testSavings("Last year's card"), testSavings("This year's card")
+	expired.Expires = time.Now().AddDate(0, -1, 0).Format("2006-01-02")
+	current.Expires = time.Now().AddDate(0, 1, 0).Format("2006-01-02")
+	p := Product{BrandName: "Glucozen", Savings: []SavingsInfo{expired, current}}
+
+	var got []Warning
+	for _, w := range p.Warnings() {
+		if w.Rule == "savings_expired" {
+			got = append(got, w)
+		}
+	}
+	if len(got) != 1 || !strings.Contains(got[0].Message, "Last year's card") {
+		t.Errorf("savings_expired warnings for one expired and one current program = %+v, want one for the expired program", got)
+	}
+}
+
+func TestLongDescriptionWarning(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        bool
+	}{
+		{"at the limit", strings.Repeat("a", maxDescriptionLength), false},
+		{"one over", strings.Repeat("a", maxDescriptionLength+1), true},
+		// é is two bytes and — three, the limit is in characters
+		{"multi-byte at the limit", strings.Repeat("é", maxDescriptionLength-1) + "—", false},
+		{"multi-byte one over", strings.Repeat("é", maxDescriptionLength) + "—", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := Product{BrandName: "Glucozen", Savings: []SavingsInfo{testSavings(tt.description)}}
+			got := slices.ContainsFunc(p.Warnings(), func(w Warning) bool { return w.Rule == "long_description" })
+			if got != tt.want {
+				t.Errorf("long_description warning for a %d character description = %t, want %t",
+					utf8.RuneCountInString(tt.description), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSavingsInfoWarnings(t *testing.T) {
+	contradictory := testSavings("Cash card")
+	contradictory.Eligibility.OtherCriteria = []string{"Cash pay only, no coupons stack"}
+	warnings := contradictory.Warnings()
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "cash_pay is true but it is false") {
+		t.Errorf("Warnings() for 'cash pay only' without cash_pay = %v, want one cash_pay contradiction", warnings)
+	}
+
+	consistent := contradictory
+	consistent.Eligibility.CashPay = true
+	if warnings := consistent.Warnings(); len(warnings) != 0 {
+		t.Errorf("Warnings() for 'cash pay only' with cash_pay = %v, want none", warnings)
+	}
+}
+
+func TestWeeklyInjectableWarning(t *testing.T) {
+	tests := []struct {
+		name string
+		p    Product
+		want bool
+	}{
+		{"daily GLP-1 injectable", Product{BrandName: "Glucozen", MedicineType: "GLP-1", AdminRoute: "Subcutaneous Injection",
+			DoseFrequency: "Once Daily"}, true},
+		{"weekly GLP-1 injectable", Product{BrandName: "Glucozen", MedicineType: "GLP-1", AdminRoute: "Subcutaneous Injection",
+			DoseFrequency: "Once Weekly"}, false},
+		{"daily GLP-1 exception", Product{BrandName: "Victoza", MedicineType: "GLP-1", AdminRoute: "Subcutaneous Injection",
+			DoseFrequency: "Once Daily"}, false},
+		{"daily GLP-1 pill", Product{BrandName: "Glucozen", MedicineType: "GLP-1", AdminRoute: "Oral Tablet",
+			DoseFrequency: "Once Daily"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := slices.ContainsFunc(tt.p.Warnings(), func(w Warning) bool { return w.Rule == "weekly_injectable" })
+			if got != tt.want {
+				t.Errorf("weekly_injectable warning for %s %s dosed '%s' = %t, want %t",
+					tt.p.BrandName, tt.p.AdminRoute, tt.p.DoseFrequency, got, tt.want)
+			}
+		})
+	}
+}
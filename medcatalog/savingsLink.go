@@ -0,0 +1,49 @@
+package medcatalog
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+// placeholderHosts are hosts left over from a template or a local test, never a real program's site
+var placeholderHosts = []string{"example.com", "example.org", "example.net", "localhost"}
+
+// placeholderTLDs are reserved for documentation and testing (RFC 2606)
+var placeholderTLDs = []string{"example", "invalid", "localhost", "test"}
+
+// savingsLinkDomains are the manufacturer domains savings links are expected to be on, a catalog's
+// config.json sets them. when they're empty any host that isn't a placeholder is accepted
+var savingsLinkDomains = []string{}
+
+// linkHostWarning describes what looks wrong with the savings link's host, or returns "" when it's
+// plausible. links that aren't URLs at all are left to validation
+func (s SavingsInfo) linkHostWarning(linkDomains []string) string {
+	if strings.TrimSpace(s.Link) == "" {
+		return ""
+	}
+	u, err := url.Parse(s.Link)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	tld := host[strings.LastIndex(host, ".")+1:]
+	switch {
+	case slices.ContainsFunc(placeholderHosts, func(h string) bool { return onDomain(host, h) }),
+		slices.Contains(placeholderTLDs, tld):
+		return fmt.Sprintf("link %s for '%s' points at placeholder host %s", s.Link, s.Description, host)
+	case net.ParseIP(host) != nil:
+		return fmt.Sprintf("link %s for '%s' points at an IP address instead of the program's site", s.Link, s.Description)
+	case len(linkDomains) > 0 && !slices.ContainsFunc(linkDomains, func(d string) bool { return onDomain(host, d) }):
+		return fmt.Sprintf("link %s for '%s' isn't on one of the savings_link_domains in %s, check it's the right site (or add %s)",
+			s.Link, s.Description, ConfigFile, host)
+	}
+	return ""
+}
+
+// onDomain reports whether host is domain or one of its subdomains
+func onDomain(host, domain string) bool {
+	return host == domain || strings.HasSuffix(host, "."+domain)
+}
@@ -0,0 +1,76 @@
+package medcatalog
+
+import (
+	"maps"
+	"slices"
+)
+
+// Settings are what a catalog's products are checked and rendered against: the built-in enum values
+// and defaults, with enums.json and config.json from the catalog directory on top. Load reads them
+// and every product it returns carries them, so catalogs loaded in the same process don't share any
+type Settings struct {
+	medicineTypes   enum
+	adminRoutes     enum
+	savingsTypes    enum
+	doseFrequencies enum
+
+	deviceMedicineTypes        enum
+	deviceRoutes               enum
+	weeklyInjectableExceptions []string
+	affordableCopayCap         float64
+	maxDescriptionLength       int
+	medicineTypeColors         map[string]string
+	savingsLinkDomains         []string
+}
+
+// DefaultSettings returns the built-in settings, what a catalog without enums.json or config.json uses
+func DefaultSettings() *Settings {
+	return &Settings{
+		medicineTypes:              slices.Clone(MedTypeEnum),
+		adminRoutes:                slices.Clone(adminRouteEnum),
+		savingsTypes:               slices.Clone(savingsTypeEnum),
+		doseFrequencies:            slices.Clone(doseFrequencyEnum),
+		deviceMedicineTypes:        slices.Clone(deviceMedicineTypes),
+		deviceRoutes:               slices.Clone(deviceRoutes),
+		weeklyInjectableExceptions: slices.Clone(weeklyInjectableExceptions),
+		affordableCopayCap:         affordableCopayCap,
+		maxDescriptionLength:       maxDescriptionLength,
+		medicineTypeColors:         maps.Clone(medicineTypeColors),
+		savingsLinkDomains:         slices.Clone(savingsLinkDomains),
+	}
+}
+
+// defaultSettings are used by products that weren't loaded with any, e.g. ones built in code. it's
+// never changed, LoadSettings starts from a fresh copy
+var defaultSettings = DefaultSettings()
+
+// LoadSettings reads enums.json and config.json from the catalog directory dir over the built-in
+// settings, either file can be left out
+func LoadSettings(dir string) (*Settings, error) {
+	s := DefaultSettings()
+	if err := s.loadEnums(dir); err != nil {
+		return nil, err
+	}
+	c, err := LoadConfig(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.apply(c); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// MedicineTypes are the recognized medicine types in the order the catalog declares them
+func (s *Settings) MedicineTypes() []string {
+	return slices.Clone(s.medicineTypes)
+}
+
+// Settings returns the settings the product was loaded with, the built-in ones for products that
+// weren't loaded
+func (p Product) Settings() *Settings {
+	if p.settings == nil {
+		return defaultSettings
+	}
+	return p.settings
+}
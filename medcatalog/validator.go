@@ -0,0 +1,121 @@
+package medcatalog
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// defaultValidatorWorkers is how many products a concurrent rule checks at once
+const defaultValidatorWorkers = 4
+
+// Rule is one validation check. Product rules look at each product on its own, Catalog rules look at
+// all of them together (e.g. for duplicates), a rule can have either or both.
+type Rule struct {
+	Name    string
+	Product func(Product) []error
+	Catalog func([]Product) []error
+	// Concurrent runs the Product check on several products at once, for rules that wait on the
+	// network. the check has to be safe to call from multiple goroutines
+	Concurrent bool
+}
+
+// Validator runs a set of rules over the catalog, collecting every problem instead of stopping at
+// the first one
+type Validator struct {
+	Rules []Rule
+	// Workers is how many products a concurrent rule checks at once, defaultValidatorWorkers if unset
+	Workers int
+}
+
+// NewValidator returns a validator that runs the rules in order
+func NewValidator(rules ...Rule) *Validator {
+	return &Validator{Rules: rules}
+}
+
+// DefaultRules are the checks Validate runs
+func DefaultRules() []Rule {
+	rules := []Rule{}
+	for _, check := range productChecks {
+		rules = append(rules, Rule{Name: check.name, Product: check.check})
+	}
+	return append(rules, Rule{Name: "duplicate_brand", Catalog: findDuplicateBrands})
+}
+
+// Add appends rules to the validator
+func (v *Validator) Add(rules ...Rule) {
+	v.Rules = append(v.Rules, rules...)
+}
+
+// Run checks the products against every rule. each error is a ValidationError with the product's
+// catalog file and the rule's name (unless the rule returned ValidationErrors itself), in rule then
+// product order even for concurrent rules. stopping ctx skips the products not checked yet.
+func (v *Validator) Run(ctx context.Context, products []Product) []error {
+	errs := []error{}
+	for _, rule := range v.Rules {
+		if rule.Product != nil {
+			perProduct := v.runProductRule(ctx, rule, products)
+			for i, productErrs := range perProduct {
+				for _, err := range productErrs {
+					errs = append(errs, asValidationError(err, products[i].sourceFile, rule.Name))
+				}
+			}
+		}
+		if rule.Catalog != nil {
+			for _, err := range rule.Catalog(products) {
+				errs = append(errs, asValidationError(err, "", rule.Name))
+			}
+		}
+	}
+	return errs
+}
+
+// runProductRule returns the rule's errors for each product, indexed like products
+func (v *Validator) runProductRule(ctx context.Context, rule Rule, products []Product) [][]error {
+	results := make([][]error, len(products))
+	if !rule.Concurrent {
+		for i, p := range products {
+			if ctx.Err() != nil {
+				break
+			}
+			results[i] = rule.Product(p)
+		}
+		return results
+	}
+
+	workers := v.Workers
+	if workers <= 0 {
+		workers = defaultValidatorWorkers
+	}
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				// each worker writes its own slots, so no locking is needed
+				results[i] = rule.Product(products[i])
+			}
+		}()
+	}
+feed:
+	for i := range products {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(jobs)
+	wg.Wait()
+	return results
+}
+
+func asValidationError(err error, file, rule string) error {
+	var ve ValidationError
+	if errors.As(err, &ve) {
+		return err
+	}
+	return ValidationError{File: file, Rule: rule, Err: err}
+}
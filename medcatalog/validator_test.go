@@ -0,0 +1,102 @@
+package medcatalog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestValidatorRun(t *testing.T) {
+	products := []Product{{BrandName: "Glucozen", sourceFile: "glucozen.json"}, {BrandName: "Sugarbane", sourceFile: "sugarbane.json"}}
+	checked := []string{}
+	v := NewValidator(
+		Rule{Name: "per_product", Product: func(p Product) []error {
+			checked = append(checked, p.BrandName)
+			return []error{errors.New("bad " + p.BrandName)}
+		}},
+		Rule{Name: "whole_catalog", Catalog: func(ps []Product) []error {
+			return []error{errors.New("bad catalog")}
+		}},
+	)
+
+	errs := v.Run(context.Background(), products)
+	want := []ValidationError{
+		{File: "glucozen.json", Rule: "per_product"},
+		{File: "sugarbane.json", Rule: "per_product"},
+		{File: "", Rule: "whole_catalog"},
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("Run() = %v, want %d errors", errs, len(want))
+	}
+	for i, err := range errs {
+		var ve ValidationError
+		if !errors.As(err, &ve) || ve.File != want[i].File || ve.Rule != want[i].Rule {
+			t.Errorf("error %d = %#v, want file %q rule %q", i, err, want[i].File, want[i].Rule)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	checked = checked[:0]
+	v.Run(ctx, products)
+	if len(checked) != 0 {
+		t.Errorf("Run() with a stopped context checked %v, want no products", checked)
+	}
+}
+
+func TestValidatorRunConcurrent(t *testing.T) {
+	products := []Product{}
+	for i := range 20 {
+		products = append(products, Product{BrandName: fmt.Sprintf("Brand %02d", i), sourceFile: fmt.Sprintf("brand%02d.json", i)})
+	}
+	var running, most atomic.Int64
+	v := NewValidator(Rule{Name: "slow", Concurrent: true, Product: func(p Product) []error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := most.Load()
+			if n <= m || most.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		// later products finish first, the errors still come back in product order
+		i := slices.IndexFunc(products, func(q Product) bool { return q.BrandName == p.BrandName })
+		time.Sleep(time.Duration(len(products)-i) * time.Millisecond)
+		return []error{errors.New("bad " + p.BrandName)}
+	}})
+	v.Workers = 3
+
+	errs := v.Run(context.Background(), products)
+	if len(errs) != len(products) {
+		t.Fatalf("Run() returned %d errors, want one per product", len(errs))
+	}
+	for i, err := range errs {
+		var ve ValidationError
+		if !errors.As(err, &ve) || ve.File != products[i].sourceFile {
+			t.Errorf("error %d = %v, want the one for %s", i, err, products[i].sourceFile)
+		}
+	}
+	if n := most.Load(); n > 3 {
+		t.Errorf("%d products checked at once, want at most Workers (3)", n)
+	}
+}
+
+func TestDefaultRulesNamed(t *testing.T) {
+	p := Product{BrandName: "Glucozen", IngredientName: "glucozide", MedicineType: "GLP-1",
+		AdminRoute: "Subcutaneous Injection", DoseFrequency: "Every Fortnight", NDCs: []string{"12345"},
+		Savings: []SavingsInfo{testSavings("Pay as little as $25")}, sourceFile: "glucozen.json"}
+	rules := map[string]int{}
+	for _, err := range NewValidator(DefaultRules()...).Run(context.Background(), []Product{p}) {
+		var ve ValidationError
+		if errors.As(err, &ve) {
+			rules[ve.Rule]++
+		}
+	}
+	if len(rules) != 2 || rules["enum_values"] != 1 || rules["identifiers"] != 1 {
+		t.Errorf("errors by rule = %v, want one each for enum_values and identifiers", rules)
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"os"
+)
+
+// relative to the root of the repo, rendered in place of the index when the catalog is empty
+const placeholderTemplateFile = "placeholder.gohtml"
+
+// parsePlaceholderTemplate returns nil without an error if there is no placeholder template
+func parsePlaceholderTemplate() (*template.Template, error) {
+	if _, err := os.Stat(repoPath + placeholderTemplateFile); errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	t, err := template.ParseFiles(repoPath + placeholderTemplateFile)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed parsing "+placeholderTemplateFile+" template"), err)
+	}
+	return t, nil
+}
+
+func renderPlaceholder(w *artifactWriter, t *template.Template) error {
+	return w.WriteFunc("index.html", func(buf *bytes.Buffer) error {
+		if err := t.Execute(buf, nil); err != nil {
+			return errors.Join(errors.New("failed executing template for placeholder index.html"), err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestPlaceholderWhenEmpty(t *testing.T) {
+	outDir := t.TempDir()
+	out, code := runMain(t, nil, "-catalog", t.TempDir(), "-out", outDir, "-skip-update-check", "-placeholder-when-empty")
+	if code != 0 {
+		t.Fatalf("exit code with an empty catalog = %d, want 0\n%s", code, out)
+	}
+	index, err := os.ReadFile(filepath.Join(outDir, "index.html"))
+	if err != nil {
+		t.Fatalf("index.html wasn't rendered: %v", err)
+	}
+	if !strings.Contains(string(index), "Coming Soon") {
+		t.Errorf("index.html for an empty catalog isn't the placeholder:\n%s", index)
+	}
+
+	// a catalog directory that isn't there is a misconfiguration, not an empty catalog
+	missing := filepath.Join(t.TempDir(), "missing")
+	if out, code = runMain(t, nil, "-catalog", missing, "-out", outDir, "-skip-update-check", "-placeholder-when-empty"); code == 0 {
+		t.Errorf("exit code with a missing catalog directory = 0, want 1\n%s", out)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// runPostRenderCmd runs a user supplied command after a successful render with the output directory
+// appended as the last argument. The command runs with the same privileges as this program, so only
+// ever pass a command you trust (never one built from catalog data or other outside input).
+func runPostRenderCmd(cmdLine string, outputDir string) error {
+	fields := strings.Fields(cmdLine)
+	if len(fields) == 0 {
+		return errors.New("post-render command is empty")
+	}
+	args := append(fields[1:], outputDir)
+	fmt.Println("running post-render command:", fields[0], strings.Join(args, " "))
+	cmd := exec.Command(fields[0], args...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return errors.Join(fmt.Errorf("post-render command '%s' failed", cmdLine), err)
+	}
+	return nil
+}
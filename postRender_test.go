@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRunPostRenderCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the test commands are shell scripts")
+	}
+	dir := t.TempDir()
+	script := filepath.Join(dir, "record.sh")
+	// records the last argument, the output directory, in the file named by the first
+	if err := os.WriteFile(script, []byte("#!/bin/sh\necho \"$2\" > \"$1\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	recorded := filepath.Join(dir, "recorded.txt")
+	outputDir := filepath.Join(dir, "public")
+
+	if err := runPostRenderCmd(script+" "+recorded, outputDir); err != nil {
+		t.Fatalf("runPostRenderCmd() failed: %v", err)
+	}
+	content, err := os.ReadFile(recorded)
+	if err != nil {
+		t.Fatalf("post-render command didn't run: %v", err)
+	}
+	if got := strings.TrimSpace(string(content)); got != outputDir {
+		t.Errorf("post-render command got %q as the output directory, want %q", got, outputDir)
+	}
+
+	if err := runPostRenderCmd("false", outputDir); err == nil {
+		t.Error("runPostRenderCmd() with a failing command returned nil, want its error")
+	}
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// productPagesDir is where per-product detail pages go, relative to the output directory
+const productPagesDir = "products/"
+
+// renderProductPages writes a detail page for each product at products/<slug>.html with every
+// eligibility criterion spelled out, the index cards only show the first few
+func renderProductPages(w *artifactWriter, products []medcatalog.Product) error {
+	t, err := loadTemplate(repoPath+"product.gohtml", func() (*template.Template, error) {
+		t, err := template.New("product.gohtml").Funcs(templateFuncs()).ParseFiles(repoPath + "product.gohtml")
+		if err != nil {
+			return nil, errors.Join(errors.New("failed parsing product.gohtml template"), err)
+		}
+		return t, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, p := range products {
+		name := productPagesDir + p.Slug + ".html"
+		err := w.WriteFunc(name, func(buf *bytes.Buffer) error {
+			if err := t.Execute(buf, p); err != nil {
+				return errors.Join(errors.New("failed executing template for "+name), err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// renderProductsJSON writes the validated and enriched catalog to products.json for downstream
+// tools. fields come out in struct order so the file diffs cleanly between runs
+func renderProductsJSON(w *artifactWriter, products []medcatalog.Product) error {
+	if products == nil {
+		products = []medcatalog.Product{}
+	}
+	content, err := json.MarshalIndent(products, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed encoding products.json"), err)
+	}
+	return w.Write("products.json", append(content, '\n'))
+}
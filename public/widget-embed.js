@@ -0,0 +1,14 @@
+// Resizes pugnare.health widget iframes to fit their content.
+// <iframe class="pugnare-widget" src="https://pugnare.health/widget.html"></iframe>
+// <script src="https://pugnare.health/widget-embed.js"></script>
+(function () {
+    window.addEventListener('message', function (e) {
+        if (!e.data || e.data.type !== 'pugnare-widget-height') return;
+        var frames = document.querySelectorAll('iframe.pugnare-widget');
+        for (var i = 0; i < frames.length; i++) {
+            if (frames[i].contentWindow === e.source) {
+                frames[i].style.height = e.data.height + 'px';
+            }
+        }
+    });
+})();
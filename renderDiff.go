@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// relative to the root of the repo, hashes of each product card from the last -render-diff run
+const fragmentHashesFile = ".render-cache/fragments.json"
+
+// renderFragmentHashes renders each product's card on its own and hashes it, keyed by slug
+func renderFragmentHashes(products []medcatalog.Product) (map[string]string, error) {
+	t, err := parseIndexTemplate()
+	if err != nil {
+		return nil, err
+	}
+	hashes := map[string]string{}
+	for _, p := range products {
+		var buf bytes.Buffer
+		if err = t.ExecuteTemplate(&buf, "drug-card", p); err != nil {
+			return nil, errors.Join(fmt.Errorf("failed rendering card for %s", p.BrandName), err)
+		}
+		sum := sha256.Sum256(buf.Bytes())
+		hashes[p.Slug] = hex.EncodeToString(sum[:])
+	}
+	return hashes, nil
+}
+
+// previewRenderDiff prints which products' cards changed since the previous run and stores the new
+// hashes for the next one
+func previewRenderDiff(products []medcatalog.Product) error {
+	current, err := renderFragmentHashes(products)
+	if err != nil {
+		return err
+	}
+	previous := map[string]string{}
+	content, err := os.ReadFile(repoPath + fragmentHashesFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return errors.Join(errors.New("failed reading "+fragmentHashesFile), err)
+	} else if err == nil {
+		if err = json.Unmarshal(content, &previous); err != nil {
+			return errors.Join(errors.New("failed parsing JSON in "+fragmentHashesFile), err)
+		}
+	}
+
+	slugs := []string{}
+	for slug := range current {
+		slugs = append(slugs, slug)
+	}
+	for slug := range previous {
+		if _, ok := current[slug]; !ok {
+			slugs = append(slugs, slug)
+		}
+	}
+	slices.Sort(slugs)
+	changed := 0
+	for _, slug := range slugs {
+		before, hadBefore := previous[slug]
+		after, hasAfter := current[slug]
+		switch {
+		case !hadBefore:
+			fmt.Println("  added:   " + slug)
+		case !hasAfter:
+			fmt.Println("  removed: " + slug)
+		case before != after:
+			fmt.Println("  changed: " + slug)
+		default:
+			continue
+		}
+		changed++
+	}
+	fmt.Printf("%d of %d product card(s) changed since the last render diff\n", changed, len(current))
+
+	content, err = json.MarshalIndent(current, "", "    ")
+	if err != nil {
+		return errors.Join(errors.New("failed encoding fragment hashes"), err)
+	}
+	if err = os.MkdirAll(filepath.Dir(repoPath+fragmentHashesFile), 0o755); err != nil {
+		return errors.Join(errors.New("failed creating render cache directory"), err)
+	}
+	if err = os.WriteFile(repoPath+fragmentHashesFile, content, 0o644); err != nil {
+		return errors.Join(errors.New("failed writing "+fragmentHashesFile), err)
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestRenderFragmentHashes(t *testing.T) {
+	products := loadTestCatalog(t)
+	before, err := renderFragmentHashes(products)
+	if err != nil {
+		t.Fatalf("renderFragmentHashes() failed: %v", err)
+	}
+	if len(before) != len(products) {
+		t.Fatalf("got %d fragment hashes for %d products", len(before), len(products))
+	}
+
+	products[0].DoseFrequency = "Once Daily"
+	after, err := renderFragmentHashes(products)
+	if err != nil {
+		t.Fatalf("renderFragmentHashes() failed: %v", err)
+	}
+	for _, p := range products {
+		changed := before[p.Slug] != after[p.Slug]
+		if want := p.Slug == products[0].Slug; changed != want {
+			t.Errorf("%s fragment changed = %t, want %t", p.Slug, changed, want)
+		}
+	}
+}
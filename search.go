@@ -0,0 +1,40 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// searchEntry is what the index's search box matches against, one per product card
+type searchEntry struct {
+	Slug           string `json:"slug"`
+	BrandName      string `json:"brand_name"`
+	IngredientName string `json:"ingredient_name"`
+	MedicineType   string `json:"medicine_type"`
+}
+
+// searchIndex is the search data embedded in index.html
+func searchIndex(products []medcatalog.Product) []searchEntry {
+	entries := make([]searchEntry, 0, len(products))
+	for _, p := range products {
+		entries = append(entries, searchEntry{
+			Slug:           p.Slug,
+			BrandName:      p.BrandName,
+			IngredientName: p.IngredientName,
+			MedicineType:   string(p.MedicineType),
+		})
+	}
+	return entries
+}
+
+// toJSON is the template helper for embedding data in a <script> block. json.Marshal escapes
+// <, > and & so the output can't close the script element early
+func toJSON(v any) (template.JS, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	return template.JS(b), nil
+}
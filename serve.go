@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// newAPIHandler exposes the in-memory catalog as a small read-only JSON API, products is called per
+// request so -watch rebuilds are picked up
+func newAPIHandler(products func() []medcatalog.Product) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/products", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, products())
+	})
+	mux.HandleFunc("GET /api/products/{slug}", func(w http.ResponseWriter, r *http.Request) {
+		slug := r.PathValue("slug")
+		for _, p := range products() {
+			if p.Slug == slug {
+				writeJSON(w, http.StatusOK, p)
+				return
+			}
+		}
+		writeJSON(w, http.StatusNotFound, map[string]string{"error": "no product with slug " + slug})
+	})
+	return mux
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fmt.Println("Error writing JSON response:", err)
+	}
+}
+
+// serve serves the rendered output in dir along with the JSON API, and live reload events when
+// reloads isn't nil. it blocks until ctx is done (SIGINT or SIGTERM in main).
+func serve(ctx context.Context, addr string, dir string, products func() []medcatalog.Product, reloads *reloadBroker) error {
+	mux := http.NewServeMux()
+	mux.Handle("/api/", newAPIHandler(products))
+	if reloads != nil {
+		mux.Handle(liveReloadPath, reloads)
+	}
+	mux.Handle("/", http.FileServer(http.Dir(dir)))
+	srv := &http.Server{Addr: addr, Handler: mux}
+	if reloads != nil {
+		// the live reload streams stay open until the browser goes away, Shutdown waits for them
+		srv.RegisterOnShutdown(reloads.Close)
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		fmt.Println("serving " + dir + " on " + addr)
+		serveErr <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return errors.Join(errors.New("failed serving site"), err)
+	case <-ctx.Done():
+	}
+	fmt.Println("shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return errors.Join(errors.New("failed shutting down server"), err)
+	}
+	return nil
+}
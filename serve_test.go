@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+func TestAPIHandler(t *testing.T) {
+	products := []medcatalog.Product{
+		{BrandName: "Glucozen", MedicineType: "GLP-1", AdminRoute: "Subcutaneous Injection", Slug: "glucozen"},
+		{BrandName: "Sugarbane", MedicineType: "SGLT-2", AdminRoute: "Oral Tablet", Slug: "sugarbane"},
+	}
+	h := newAPIHandler(func() []medcatalog.Product { return products })
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("GET %s Content-Type = %q, want application/json", path, ct)
+		}
+		return rec
+	}
+
+	rec := get("/api/products")
+	var all []medcatalog.Product
+	if err := json.Unmarshal(rec.Body.Bytes(), &all); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/products = %d %q (%v), want 200 with the products", rec.Code, rec.Body, err)
+	}
+	if len(all) != 2 || all[1].Slug != "sugarbane" {
+		t.Errorf("GET /api/products returned %+v, want both products", all)
+	}
+
+	rec = get("/api/products/sugarbane")
+	var one medcatalog.Product
+	if err := json.Unmarshal(rec.Body.Bytes(), &one); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("GET /api/products/sugarbane = %d %q (%v), want 200 with the product", rec.Code, rec.Body, err)
+	}
+	if one.BrandName != "Sugarbane" {
+		t.Errorf("GET /api/products/sugarbane returned %s", one.BrandName)
+	}
+
+	rec = get("/api/products/missing")
+	var body map[string]string
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil || rec.Code != http.StatusNotFound || body["error"] == "" {
+		t.Errorf("GET /api/products/missing = %d %q, want 404 with a JSON error", rec.Code, rec.Body)
+	}
+
+	// products is read per request, so a -watch rebuild shows up without restarting
+	products = products[:1]
+	rec = get("/api/products/sugarbane")
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("GET /api/products/sugarbane after it was removed = %d, want 404", rec.Code)
+	}
+}
+
+func TestServeShutsDownWithLiveReloadOpen(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	served := make(chan error, 1)
+	go func() {
+		served <- serve(ctx, addr, t.TempDir(), func() []medcatalog.Product { return nil }, newReloadBroker())
+	}()
+
+	// a browser tab with the live reload stream open
+	var resp *http.Response
+	for range 50 {
+		if resp, err = http.Get("http://" + addr + liveReloadPath); err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("connecting to the live reload stream failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	cancel()
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("serve() shutting down with a live reload stream open = %v, want nil", err)
+		}
+	case <-time.After(3 * time.Second):
+		t.Fatal("serve() didn't shut down with a live reload stream open")
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// siteTemplate is an index layout and the file in the output directory it's rendered to
+type siteTemplate struct {
+	File   string
+	Output string
+}
+
+// indexTemplate is what's rendered when no -template flags are given
+var indexTemplate = siteTemplate{File: repoPath + "index.gohtml", Output: "index.html"}
+
+// siteTemplates collects the repeatable -template flag, each value is template=output
+type siteTemplates []siteTemplate
+
+func (s *siteTemplates) String() string {
+	pairs := []string{}
+	for _, t := range *s {
+		pairs = append(pairs, t.File+"="+t.Output)
+	}
+	return strings.Join(pairs, ",")
+}
+
+func (s *siteTemplates) Set(value string) error {
+	file, output, ok := strings.Cut(value, "=")
+	if !ok || file == "" || output == "" {
+		return fmt.Errorf("'%s' should be template=output, e.g. print.gohtml=print.html", value)
+	}
+	// outputs are written inside the output directory, never above it
+	if !filepath.IsLocal(output) {
+		return fmt.Errorf("output '%s' has to be a relative path inside the output directory", output)
+	}
+	for _, t := range *s {
+		if t.Output == output {
+			return fmt.Errorf("output '%s' is already rendered from %s", output, t.File)
+		}
+	}
+	*s = append(*s, siteTemplate{File: file, Output: filepath.ToSlash(output)})
+	return nil
+}
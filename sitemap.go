@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+const sitemapNamespace = "http://www.sitemaps.org/schemas/sitemap/0.9"
+
+type sitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type sitemapURLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	URLs    []sitemapURL `xml:"url"`
+}
+
+// parseSiteBaseURL checks that the -base-url flag is an absolute https URL, pages are joined onto it
+func parseSiteBaseURL(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, errors.Join(fmt.Errorf("failed parsing base URL '%s'", raw), err)
+	}
+	if u.Scheme != "https" || u.Host == "" {
+		return nil, fmt.Errorf("base URL '%s' must be an absolute https URL", raw)
+	}
+	if u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("base URL '%s' can't have a query or fragment", raw)
+	}
+	return u, nil
+}
+
+// labelLastMod returns the FDA label updated date if it's a valid YYYY-MM-DD date, sitemaps reject
+// anything else
+func labelLastMod(p medcatalog.Product) string {
+	if _, err := time.Parse(time.DateOnly, p.FDALabelUpdated); err != nil {
+		return ""
+	}
+	return p.FDALabelUpdated
+}
+
+// renderSitemap writes sitemap.xml listing the index and each product page under baseURL. product
+// pages use their FDA label updated date as lastmod and the index uses the most recent of those
+func renderSitemap(w *artifactWriter, baseURL *url.URL, products []medcatalog.Product) error {
+	set := sitemapURLSet{Xmlns: sitemapNamespace}
+	index := sitemapURL{Loc: baseURL.JoinPath("index.html").String()}
+	set.URLs = append(set.URLs, index)
+	for _, p := range products {
+		lastMod := labelLastMod(p)
+		// dates are YYYY-MM-DD so comparing the strings compares the dates
+		if lastMod > set.URLs[0].LastMod {
+			set.URLs[0].LastMod = lastMod
+		}
+		set.URLs = append(set.URLs, sitemapURL{
+			Loc:     baseURL.JoinPath(productPagesDir, p.Slug+".html").String(),
+			LastMod: lastMod,
+		})
+	}
+
+	content, err := xml.MarshalIndent(set, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed encoding sitemap.xml"), err)
+	}
+	return w.Write("sitemap.xml", append([]byte(xml.Header), append(content, '\n')...))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"time"
+)
+
+// runSummary is a structured record of a run for dashboards, unlike the console output it's meant
+// to be charted over time
+type runSummary struct {
+	StartedAt           time.Time      `json:"started_at"`
+	DurationSeconds     float64        `json:"duration_seconds"`
+	Success             bool           `json:"success"`
+	Products            int            `json:"products"`
+	ErrorsByRule        map[string]int `json:"errors_by_rule"`
+	WarningsByRule      map[string]int `json:"warnings_by_rule"`
+	FDAChecks           int            `json:"fda_checks"`
+	LabelsNeedingUpdate int            `json:"labels_needing_update"`
+	CacheHits           int            `json:"cache_hits"`
+	CacheHitRate        float64        `json:"cache_hit_rate"`
+}
+
+func newRunSummary() *runSummary {
+	return &runSummary{
+		StartedAt:      time.Now(),
+		ErrorsByRule:   map[string]int{},
+		WarningsByRule: map[string]int{},
+	}
+}
+
+// warningCount is how many warnings the run reported across every rule
+func (s *runSummary) warningCount() int {
+	total := 0
+	for _, n := range s.WarningsByRule {
+		total += n
+	}
+	return total
+}
+
+// write records the summary as a single line of JSON, appending lets a file hold the history of runs
+func (s *runSummary) write(path string, appendToFile bool) error {
+	s.DurationSeconds = time.Since(s.StartedAt).Seconds()
+	if s.FDAChecks > 0 {
+		s.CacheHitRate = float64(s.CacheHits) / float64(s.FDAChecks)
+	}
+	content, err := json.Marshal(s)
+	if err != nil {
+		return errors.Join(errors.New("failed encoding run summary"), err)
+	}
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if appendToFile {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	}
+	f, err := os.OpenFile(path, flags, 0o644)
+	if err != nil {
+		return errors.Join(errors.New("failed opening summary file "+path), err)
+	}
+	defer f.Close()
+	if _, err = f.Write(append(content, '\n')); err != nil {
+		return errors.Join(errors.New("failed writing summary file "+path), err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"html/template"
+	"os"
+	"sync"
+	"time"
+)
+
+// parsed templates are reused until their file changes, so -watch rebuilds don't re-read and
+// re-parse every template on each edit to the catalog
+type cachedTemplate struct {
+	modTime time.Time
+	size    int64
+	t       *template.Template
+}
+
+var (
+	templateCacheMu sync.Mutex
+	templateCache   = map[string]cachedTemplate{}
+)
+
+// loadTemplate returns the template parsed from file, calling parse only when the file's mtime or
+// size differs from the last time it was parsed
+func loadTemplate(file string, parse func() (*template.Template, error)) (*template.Template, error) {
+	info, err := os.Stat(file)
+	if err != nil {
+		return nil, errors.Join(errors.New("failed reading "+file), err)
+	}
+	templateCacheMu.Lock()
+	defer templateCacheMu.Unlock()
+	if cached, ok := templateCache[file]; ok && cached.modTime.Equal(info.ModTime()) && cached.size == info.Size() {
+		return cached.t, nil
+	}
+	t, err := parse()
+	if err != nil {
+		return nil, err
+	}
+	templateCache[file] = cachedTemplate{modTime: info.ModTime(), size: info.Size(), t: t}
+	return t, nil
+}
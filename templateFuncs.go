@@ -0,0 +1,82 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/language"
+)
+
+// templateFuncs are the helpers every page template can use
+func templateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"hasPrefix": strings.HasPrefix,
+		"truncate":  truncate,
+		"subtract": func(a, b int) int {
+			return a - b
+		},
+		"searchIndex": searchIndex,
+		"toJSON":      toJSON,
+		"formatDate":  formatDate,
+		"currency":    currency,
+		"titleCase":   titleCase,
+		"join":        strings.Join,
+		"lower":       strings.ToLower,
+	}
+}
+
+// truncate shortens s to at most n characters followed by "...", cutting at the last space before
+// the limit so words aren't chopped. it counts runes so an é or — is never split into invalid UTF-8.
+// a limit of 0 or less leaves only the "..."
+func truncate(s string, n int) string {
+	n = max(n, 0)
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	cut := n
+	for i := n; i > 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace) + "..."
+}
+
+// formatDate renders a YYYY-MM-DD date like January 2, 2006, anything else is returned as is
+func formatDate(date string) string {
+	t, err := time.Parse("2006-01-02", date)
+	if err != nil {
+		return date
+	}
+	return t.Format("January 2, 2006")
+}
+
+// currency renders a dollar amount like $1,250, with cents only when there are some ($24.50)
+func currency(amount float64) string {
+	cents := int64(math.Round(amount * 100))
+	sign := ""
+	if cents < 0 {
+		sign, cents = "-", -cents
+	}
+	dollars := strconv.FormatInt(cents/100, 10)
+	for i := len(dollars) - 3; i > 0; i -= 3 {
+		dollars = dollars[:i] + "," + dollars[i:]
+	}
+	if cents%100 == 0 {
+		return sign + "$" + dollars
+	}
+	return fmt.Sprintf("%s$%s.%02d", sign, dollars, cents%100)
+}
+
+// titleCase capitalizes the first letter of each word, e.g. for generic names the FDA writes in caps
+func titleCase(s string) string {
+	return cases.Title(language.English).String(s)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestTemplateFuncs(t *testing.T) {
+	tests := []struct {
+		name string
+		tmpl string
+		data any
+		want string
+	}{
+		{"formatDate", `{{formatDate .}}`, "2025-03-01", "March 1, 2025"},
+		{"formatDate not a date", `{{formatDate .}}`, "Spring 2025", "Spring 2025"},
+		{"formatDate empty", `{{formatDate .}}`, "", ""},
+		{"titleCase", `{{titleCase .}}`, "SEMAGLUTIDE INJECTION", "Semaglutide Injection"},
+		{"titleCase lowercase", `{{titleCase .}}`, "insulin glargine-yfgn", "Insulin Glargine-Yfgn"},
+		{"join", `{{join . ", "}}`, []string{"1991302", "1991306"}, "1991302, 1991306"},
+		{"join empty", `{{join . ", "}}`, []string{}, ""},
+		{"lower", `{{lower .}}`, "GLP-1", "glp-1"},
+		{"currency whole dollars", `{{currency .}}`, 25.0, "$25"},
+		{"currency cents", `{{currency .}}`, 24.5, "$24.50"},
+		{"currency thousands", `{{currency .}}`, 1250.0, "$1,250"},
+		{"currency rounds to the cent", `{{currency .}}`, 9.999, "$10"},
+		{"currency zero", `{{currency .}}`, 0.0, "$0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl := template.Must(template.New(tt.name).Funcs(templateFuncs()).Parse(tt.tmpl))
+			var b strings.Builder
+			if err := tmpl.Execute(&b, tt.data); err != nil {
+				t.Fatalf("executing %s failed: %v", tt.tmpl, err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("%s with %v = %q, want %q", tt.tmpl, tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+		n    int
+		want string
+	}{
+		{"short enough", "Pay as little as $25", 20, "Pay as little as $25"},
+		{"word boundary", "Pay as little as $25 a month", 15, "Pay as little..."},
+		{"no space", "Glucozenextendedrelease", 8, "Glucozen..."},
+		{"é at the cut", "Café card", 4, "Café..."},
+		{"é past the cut", "Caféteria", 4, "Café..."},
+		{"— at the cut", "Save—now", 5, "Save—..."},
+		{"space after —", "Save — up to $500", 6, "Save —..."},
+		{"zero", "Pay as little as $25", 0, "..."},
+		{"negative", "Pay as little as $25", -1, "..."},
+		{"empty", "", 0, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncate(tt.s, tt.n)
+			if got != tt.want {
+				t.Errorf("truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+			}
+			if !utf8.ValidString(got) {
+				t.Errorf("truncate(%q, %d) = %q, which isn't valid UTF-8", tt.s, tt.n, got)
+			}
+		})
+	}
+}
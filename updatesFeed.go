@@ -0,0 +1,108 @@
+package main
+
+import (
+	"cmp"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+const atomNamespace = "http://www.w3.org/2005/Atom"
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID      string    `xml:"id"`
+	Title   string    `xml:"title"`
+	Updated string    `xml:"updated"`
+	Link    *atomLink `xml:"link,omitempty"`
+	Summary string    `xml:"summary"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"feed"`
+	Xmlns   string      `xml:"xmlns,attr"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+// atomDate turns a YYYY-MM-DD date into the RFC 3339 timestamp Atom wants
+func atomDate(date string) (string, bool) {
+	t, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return "", false
+	}
+	return t.UTC().Format(time.RFC3339), true
+}
+
+// renderUpdatesFeed writes updates.xml, an Atom feed with an entry for each product whose FDA label
+// is newer than the one we link to, newest first. products should be as the FDA check left them,
+// before any write-back. everything in it comes from the catalog and label dates so re-rendering the
+// same data gives the same file
+func renderUpdatesFeed(w *artifactWriter, baseURL *url.URL, products []medcatalog.Product) error {
+	feed := atomFeed{
+		Xmlns:  atomNamespace,
+		ID:     "urn:pugnarehealth:fda-label-updates",
+		Title:  "Pugnare.Health FDA label updates",
+		Author: "Pugnare.Health",
+	}
+	if baseURL != nil {
+		feed.ID = baseURL.JoinPath("updates.xml").String()
+		feed.Links = []atomLink{
+			{Href: feed.ID, Rel: "self"},
+			{Href: baseURL.JoinPath("index.html").String(), Rel: "alternate"},
+		}
+	}
+
+	updated := slices.DeleteFunc(slices.Clone(products), func(p medcatalog.Product) bool {
+		_, ok := atomDate(p.FDALabelLatest)
+		return !p.FDALabelNeedsUpdate || !ok
+	})
+	slices.SortStableFunc(updated, func(a, b medcatalog.Product) int {
+		return cmp.Compare(b.FDALabelLatest, a.FDALabelLatest)
+	})
+	for _, p := range updated {
+		date, _ := atomDate(p.FDALabelLatest)
+		entry := atomEntry{
+			ID:      fmt.Sprintf("urn:pugnarehealth:fda-label-update:%s:%s", p.Slug, p.FDALabelLatest),
+			Title:   fmt.Sprintf("%s label updated %s", p.BrandName, p.FDALabelLatest),
+			Updated: date,
+			Summary: fmt.Sprintf("The FDA label for %s (%s) has a new effective date of %s.", p.BrandName, p.IngredientName,
+				p.FDALabelLatest),
+		}
+		if p.FDALabelUpdated != "" {
+			entry.Summary += fmt.Sprintf(" The label we link to is from %s.", p.FDALabelUpdated)
+		}
+		if p.FDALabelFile != "" {
+			entry.Link = &atomLink{Href: p.FDALabelFile, Rel: "alternate"}
+		}
+		feed.Entries = append(feed.Entries, entry)
+	}
+
+	// the feed is as new as the newest label date we know of, which keeps it stable between runs
+	newest := ""
+	for _, p := range products {
+		newest = max(newest, p.FDALabelLatest, p.FDALabelUpdated)
+	}
+	if feed.Updated, _ = atomDate(newest); feed.Updated == "" {
+		feed.Updated = time.Unix(0, 0).UTC().Format(time.RFC3339)
+	}
+
+	content, err := xml.MarshalIndent(feed, "", "  ")
+	if err != nil {
+		return errors.Join(errors.New("failed encoding updates.xml"), err)
+	}
+	return w.Write("updates.xml", append([]byte(xml.Header), append(content, '\n')...))
+}
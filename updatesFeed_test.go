@@ -0,0 +1,45 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+func TestRenderUpdatesFeed(t *testing.T) {
+	dir := t.TempDir()
+	products := []medcatalog.Product{
+		{BrandName: "Glucozen", Slug: "glucozen", FDALabelUpdated: "2025-01-01", FDALabelLatest: "2025-03-01", FDALabelNeedsUpdate: true},
+		{BrandName: "Sugarbane", Slug: "sugarbane", FDALabelUpdated: "2024-01-15", FDALabelLatest: "2024-01-15"},
+	}
+	if err := renderUpdatesFeed(newArtifactWriter(dir), nil, products); err != nil {
+		t.Fatalf("renderUpdatesFeed() failed: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(dir, "updates.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var feed atomFeed
+	if err = xml.Unmarshal(content, &feed); err != nil {
+		t.Fatalf("updates.xml isn't XML: %v", err)
+	}
+	if len(feed.Entries) != 1 || feed.Entries[0].Title != "Glucozen label updated 2025-03-01" {
+		t.Errorf("feed entries = %+v, want just Glucozen's update", feed.Entries)
+	}
+	if feed.Updated != "2025-03-01T00:00:00Z" {
+		t.Errorf("feed updated = %s, want the newest label date", feed.Updated)
+	}
+}
+
+func TestUpdatesFeedNeedsFDACheck(t *testing.T) {
+	outDir := t.TempDir()
+	if out, code := runMain(t, nil, "-catalog", "testdata/catalog", "-out", outDir, "-skip-update-check"); code != 0 {
+		t.Fatalf("exit code = %d, want 0\n%s", code, out)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "updates.xml")); !os.IsNotExist(err) {
+		t.Errorf("updates.xml was written without the FDA check (stat error %v)", err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"runtime/debug"
+	"strings"
+)
+
+// set when building a release, e.g.
+// go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse HEAD) -X main.buildDate=$(date -u +%FT%TZ)"
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+// buildInfo identifies the build that produced a site
+type buildInfo struct {
+	Version string `json:"version"`
+	Commit  string `json:"commit"`
+	Date    string `json:"build_date"`
+}
+
+// currentBuild is the -ldflags values, anything not set falls back to what go build stamped into the
+// binary: a pseudo-version with the commit when built from a git checkout, the commit and its time
+func currentBuild() buildInfo {
+	b := buildInfo{Version: version, Commit: commit, Date: buildDate}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if b.Version == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			b.Version = info.Main.Version
+		}
+		for _, s := range info.Settings {
+			switch {
+			case s.Key == "vcs.revision" && b.Commit == "":
+				b.Commit = s.Value
+			case s.Key == "vcs.time" && b.Date == "":
+				b.Date = s.Value
+			}
+		}
+	}
+	if b.Version == "" {
+		b.Version = "devel"
+	}
+	if b.Commit == "" {
+		b.Commit = "unknown"
+	}
+	if b.Date == "" {
+		b.Date = "unknown"
+	}
+	return b
+}
+
+func (b buildInfo) String() string {
+	return fmt.Sprintf("pugnarehealth %s (commit %s, built %s)", b.Version, b.Commit, b.Date)
+}
+
+// htmlComment is the build as an HTML comment, html/template strips comments written in the template
+// itself so it has to be passed in. "--" can't appear inside a comment
+func (b buildInfo) htmlComment() template.HTML {
+	return template.HTML("<!-- generated by " + strings.ReplaceAll(b.String(), "--", "- -") + " -->")
+}
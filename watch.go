@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+const watchInterval = 500 * time.Millisecond
+
+// where the -watch reload script listens for server-sent events
+const liveReloadPath = "/__livereload"
+
+// watchFingerprint summarizes the size and mtime of everything a rebuild reads, polling it is plenty
+// for a handful of files and avoids a file watcher dependency
+func watchFingerprint(catalogDir string, templates []siteTemplate) string {
+	var b strings.Builder
+	// config.json is in the catalog directory so the walk picks it up
+	paths := []string{repoPath + "product.gohtml"}
+	for _, t := range templates {
+		paths = append(paths, t.File)
+	}
+	_ = filepath.WalkDir(catalogDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			paths = append(paths, path)
+		}
+		return nil
+	})
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(&b, "%s:%d:%d\n", path, info.Size(), info.ModTime().UnixNano())
+		}
+	}
+	return b.String()
+}
+
+// watchForChanges calls rebuild whenever the catalog or a template changes, until ctx is done
+func watchForChanges(ctx context.Context, catalogDir string, templates []siteTemplate, rebuild func()) {
+	fmt.Println("watching " + catalogDir + " and the templates for changes...")
+	last := watchFingerprint(catalogDir, templates)
+	ticker := time.NewTicker(watchInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if current := watchFingerprint(catalogDir, templates); current != last {
+			last = current
+			fmt.Println("change detected, rebuilding...")
+			rebuild()
+		}
+	}
+}
+
+// carryOverFDAFields copies what the FDA check (and -download-labels) filled in on the initial build
+// onto a -watch rebuild's products, matched by slug, since rebuilds skip the lookups. whether the
+// label needs an update is worked out again in case the recorded date was edited
+func carryOverFDAFields(rebuilt []medcatalog.Product, enriched []medcatalog.Product) {
+	bySlug := map[string]medcatalog.Product{}
+	for _, p := range enriched {
+		bySlug[p.Slug] = p
+	}
+	for i, p := range rebuilt {
+		e, ok := bySlug[p.Slug]
+		if !ok || p.SkipFDALookup() || e.SkipFDALookup() {
+			continue // new since the initial build, or not checked
+		}
+		rebuilt[i].FDALabelLatest = e.FDALabelLatest
+		rebuilt[i].FDALabelRecencyNotFound = e.FDALabelRecencyNotFound
+		rebuilt[i].BoxedWarning = e.BoxedWarning
+		rebuilt[i].Manufacturer = e.Manufacturer
+		rebuilt[i].FDAApplicationNumber = e.FDAApplicationNumber
+		if len(p.RxCUIs) == 0 {
+			rebuilt[i].RxCUIs = e.RxCUIs
+		}
+		if p.FDALabelFile == e.FDALabelFile {
+			rebuilt[i].FDALabelLocalFile = e.FDALabelLocalFile
+		}
+		// dates are YYYY-MM-DD so they compare as strings
+		rebuilt[i].FDALabelNeedsUpdate = p.FDALabelUpdated == "" || e.FDALabelLatest > p.FDALabelUpdated
+	}
+}
+
+// reloadBroker tells connected browsers to reload over server-sent events after a rebuild
+type reloadBroker struct {
+	mu      sync.Mutex
+	clients map[chan struct{}]struct{}
+	// closed ends every open event stream, the server's shutdown waits on them otherwise
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+func newReloadBroker() *reloadBroker {
+	return &reloadBroker{clients: map[chan struct{}]struct{}{}, closed: make(chan struct{})}
+}
+
+// Close disconnects the connected browsers, it's registered to run when the server shuts down
+func (b *reloadBroker) Close() {
+	b.closeOnce.Do(func() { close(b.closed) })
+}
+
+func (b *reloadBroker) Notify() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for c := range b.clients {
+		select {
+		case c <- struct{}{}:
+		default: // a reload is already pending for this client
+		}
+	}
+}
+
+func (b *reloadBroker) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	c := make(chan struct{}, 1)
+	b.mu.Lock()
+	b.clients[c] = struct{}{}
+	b.mu.Unlock()
+	defer func() {
+		b.mu.Lock()
+		delete(b.clients, c)
+		b.mu.Unlock()
+	}()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-b.closed:
+			return
+		case <-c:
+			fmt.Fprint(w, "data: reload\n\n")
+			flusher.Flush()
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+func TestCarryOverFDAFields(t *testing.T) {
+	enriched := []medcatalog.Product{
+		{BrandName: "Glucozen", Slug: "glucozen", MedicineType: "GLP-1", FDALabelUpdated: "2025-01-01",
+			FDALabelLatest: "2025-03-01", FDALabelNeedsUpdate: true, Manufacturer: "Glucozen Pharma",
+			BoxedWarning: "WARNING", RxCUIs: []string{"1991302"}},
+		{BrandName: "Sugarbane", Slug: "sugarbane", MedicineType: "SGLT-2", FDALabelUpdated: "2024-01-15",
+			FDALabelLatest: "2024-01-15", Manufacturer: "Sugarbane Labs"},
+	}
+	rebuilt := []medcatalog.Product{
+		// the new label date was recorded while watching
+		{BrandName: "Glucozen", Slug: "glucozen", MedicineType: "GLP-1", FDALabelUpdated: "2025-03-01"},
+		{BrandName: "Sugarbane", Slug: "sugarbane", MedicineType: "SGLT-2", FDALabelUpdated: "2024-01-15"},
+		{BrandName: "Newzen", Slug: "newzen", MedicineType: "GLP-1"},
+	}
+	carryOverFDAFields(rebuilt, enriched)
+
+	if rebuilt[0].FDALabelLatest != "2025-03-01" || rebuilt[0].Manufacturer != "Glucozen Pharma" ||
+		rebuilt[0].BoxedWarning == "" || !slices.Equal(rebuilt[0].RxCUIs, []string{"1991302"}) {
+		t.Errorf("Glucozen didn't keep its FDA fields: %+v", rebuilt[0])
+	}
+	if rebuilt[0].FDALabelNeedsUpdate {
+		t.Error("Glucozen still needs an update after the latest label date was recorded")
+	}
+	if rebuilt[1].Manufacturer != "Sugarbane Labs" || rebuilt[1].FDALabelNeedsUpdate {
+		t.Errorf("Sugarbane didn't keep its FDA fields: %+v", rebuilt[1])
+	}
+	if rebuilt[2].FDALabelLatest != "" || rebuilt[2].FDALabelNeedsUpdate {
+		t.Errorf("product added while watching got FDA fields it was never checked for: %+v", rebuilt[2])
+	}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+
+	"github.com/samiam2013/pugnarehealth/medcatalog"
+)
+
+// renderWidget writes a small, dependency free product list meant to be embedded in an iframe on
+// partner sites, sized by public/widget-embed.js
+func renderWidget(w *artifactWriter, products []medcatalog.Product) error {
+	t, err := template.ParseFiles(repoPath + "widget.gohtml")
+	if err != nil {
+		return errors.Join(errors.New("failed parsing widget.gohtml template"), err)
+	}
+
+	data := struct {
+		Products []medcatalog.Product
+	}{
+		Products: products,
+	}
+	return w.WriteFunc("widget.html", func(buf *bytes.Buffer) error {
+		if err := t.Execute(buf, data); err != nil {
+			return errors.Join(errors.New("failed executing template for widget.html"), err)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the golden files in testdata/ with the current output")
+
+func TestRenderWidgetGolden(t *testing.T) {
+	dir := t.TempDir()
+	if err := renderWidget(newArtifactWriter(dir), loadTestCatalog(t)); err != nil {
+		t.Fatalf("renderWidget() failed: %v", err)
+	}
+	got, err := os.ReadFile(filepath.Join(dir, "widget.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := filepath.Join("testdata", "widget.golden.html")
+	if *updateGolden {
+		if err = os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("failed reading %s, run with -update to create it: %v", golden, err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("widget.html doesn't match %s, run with -update if the change is intended\ngot:\n%s", golden, got)
+	}
+}